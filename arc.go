@@ -0,0 +1,183 @@
+package joint
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"path"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/nwaples/rardecode/v2"
+)
+
+// openbase opens file at base joint, and returns its size.
+func openbase(base Joint, fpath string) (Joint, int64, error) {
+	if base == nil {
+		base = &SysJoint{}
+	}
+	if _, err := base.Open(fpath); err != nil {
+		return nil, 0, err
+	}
+	var size, err = base.Size()
+	if err != nil {
+		base.Close()
+		return nil, 0, err
+	}
+	return base, size, nil
+}
+
+// ZipJoint opens ZIP-archive placed at base joint and provides
+// read-only access to its files.
+// Key is external path to archive file.
+type ZipJoint struct {
+	Base Joint
+	FSJoint
+}
+
+func (j *ZipJoint) Make(base Joint, arcpath string) (err error) {
+	var size int64
+	if j.Base, size, err = openbase(base, arcpath); err != nil {
+		return
+	}
+	if j.FS, err = zip.NewReader(j.Base, size); err != nil {
+		return
+	}
+	return
+}
+
+func (j *ZipJoint) Cleanup() (err error) {
+	j.FSJoint.Cleanup()
+	if j.Base != nil {
+		err = j.Base.Cleanup()
+		j.Base = nil
+	}
+	return err
+}
+
+func (j *ZipJoint) Stat() (fs.FileInfo, error) {
+	if j.path == "." { // base archive
+		return statbase(j.Base)
+	}
+	return j.FSJoint.Stat()
+}
+
+// SevenZipJoint opens 7z-archive placed at base joint and provides
+// read-only access to its files. Solid blocks of archive are
+// decompressed once on sequential access to files at the same block,
+// decompressor is kept for next file in block.
+// Key is external path to archive file.
+type SevenZipJoint struct {
+	Base Joint
+	FSJoint
+}
+
+func (j *SevenZipJoint) Make(base Joint, arcpath string) (err error) {
+	var size int64
+	if j.Base, size, err = openbase(base, arcpath); err != nil {
+		return
+	}
+	if j.FS, err = sevenzip.NewReader(j.Base, size); err != nil {
+		return
+	}
+	return
+}
+
+func (j *SevenZipJoint) Cleanup() (err error) {
+	j.FSJoint.Cleanup()
+	if j.Base != nil {
+		err = j.Base.Cleanup()
+		j.Base = nil
+	}
+	return err
+}
+
+func (j *SevenZipJoint) Stat() (fs.FileInfo, error) {
+	if j.path == "." { // base archive
+		return statbase(j.Base)
+	}
+	return j.FSJoint.Stat()
+}
+
+// rarvol is file system with single volume of RAR-archive.
+type rarvol struct {
+	name string
+	fi   fs.FileInfo
+	r    io.ReaderAt
+	size int64
+}
+
+func (v *rarvol) Open(fpath string) (fs.File, error) {
+	if fpath != v.name {
+		return nil, &fs.PathError{Op: "open", Path: fpath, Err: fs.ErrNotExist}
+	}
+	return &imgfile{fi: v.fi, SectionReader: io.NewSectionReader(v.r, 0, v.size)}, nil
+}
+
+// rarfs hides seeker of files at RAR-archive, it does not
+// update read position of stored files.
+type rarfs struct {
+	*rardecode.RarFS
+}
+
+type rarfile struct {
+	fs.File
+}
+
+func (rfs rarfs) Open(fpath string) (fs.File, error) {
+	var f, err = rfs.RarFS.Open(fpath)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := f.(fs.ReadDirFile); ok {
+		return f, nil
+	}
+	return rarfile{f}, nil
+}
+
+// RarJoint opens RAR-archive of version 4.x or 5.x placed at base
+// joint and provides read-only access to its files. Multi-volume
+// archives are not supported.
+// Key is external path to archive file.
+type RarJoint struct {
+	Base Joint
+	FSJoint
+}
+
+func (j *RarJoint) Make(base Joint, arcpath string) (err error) {
+	var size int64
+	if j.Base, size, err = openbase(base, arcpath); err != nil {
+		return
+	}
+	var fi fs.FileInfo
+	if fi, err = j.Base.Stat(); err != nil {
+		return
+	}
+	var vol = &rarvol{
+		name: path.Base(arcpath),
+		fi:   fi,
+		r:    j.Base,
+		size: size,
+	}
+	var rfs *rardecode.RarFS
+	if rfs, err = rardecode.OpenFS(vol.name, rardecode.FileSystem(vol)); err != nil {
+		return
+	}
+	j.FS = rarfs{rfs}
+	return
+}
+
+func (j *RarJoint) Cleanup() (err error) {
+	j.FSJoint.Cleanup()
+	if j.Base != nil {
+		err = j.Base.Cleanup()
+		j.Base = nil
+	}
+	return err
+}
+
+func (j *RarJoint) Stat() (fs.FileInfo, error) {
+	if j.path == "." { // base archive
+		return statbase(j.Base)
+	}
+	return j.FSJoint.Stat()
+}
@@ -0,0 +1,128 @@
+package joint_test
+
+import (
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// Archives with the same content, and makers of joints for them.
+var arcjoints = map[string]func() jnt.Joint{
+	"testdata/archive.zip":  func() jnt.Joint { return &jnt.ZipJoint{} },
+	"testdata/archive.7z":   func() jnt.Joint { return &jnt.SevenZipJoint{} },
+	"testdata/solid.7z":     func() jnt.Joint { return &jnt.SevenZipJoint{} },
+	"testdata/archive.rar":  func() jnt.Joint { return &jnt.RarJoint{} }, // RAR 5.x
+	"testdata/archive4.rar": func() jnt.Joint { return &jnt.RarJoint{} }, // RAR 4.x
+	"testdata/archive.tar":  func() jnt.Joint { return &jnt.TarJoint{} },
+}
+
+// Files list in archives.
+var arcfiles = []string{
+	"fox.txt",
+	"data/lorem1.txt",
+	"data/рыба.txt",
+	"data/docs/doc1.txt",
+	"data/доки/док2.txt",
+}
+
+// Directories list in archives.
+var arcdirs = map[string][]string{
+	"":          {"fox.txt", "data"},
+	"data":      {"lorem1.txt", "рыба.txt", "docs", "доки"},
+	"data/docs": {"doc1.txt"},
+	"data/доки": {"док2.txt"},
+}
+
+// Check file reading in archives placed at primary filesystem.
+func TestArcReadFile(t *testing.T) {
+	for arcpath, maker := range arcjoints {
+		var j = maker()
+		if err := j.Make(nil, arcpath); err != nil {
+			t.Fatal(err)
+		}
+		for _, fpath := range arcfiles {
+			if err := checkFile(j, fpath); err != nil {
+				t.Fatalf("%s: %v", arcpath, err)
+			}
+		}
+		j.Cleanup()
+	}
+}
+
+// Check directory list in archives placed at primary filesystem.
+func TestArcDirList(t *testing.T) {
+	for arcpath, maker := range arcjoints {
+		var j = maker()
+		if err := j.Make(nil, arcpath); err != nil {
+			t.Fatal(err)
+		}
+		for fpath := range arcdirs {
+			if err := checkDir(j, fpath, arcdirs); err != nil {
+				t.Fatalf("%s: %v", arcpath, err)
+			}
+		}
+		j.Cleanup()
+	}
+}
+
+// Read files chunks from compressed files at archives.
+func TestArcReadChunk(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	for arcpath := range arcjoints {
+		var f, err = jp.Open(arcpath + "/fox.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var j = f.(jnt.Joint)
+
+		var b1 [9]byte // buffer for "brown fox" chunk from file content
+		if _, err = j.ReadAt(b1[:], 10); err != nil {
+			t.Fatal(err)
+		}
+		if string(b1[:]) != "brown fox" {
+			t.Fatalf("%s: read string does not match to pattern", arcpath)
+		}
+
+		var b2 [8]byte // buffer for "lazy dog" chunk from file content
+		if _, err = j.Seek(35, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = j.Read(b2[:]); err != nil {
+			t.Fatal(err)
+		}
+		if string(b2[:]) != "lazy dog" {
+			t.Fatalf("%s: read string does not match to pattern", arcpath)
+		}
+		f.Close()
+	}
+}
+
+func TestArcPool(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	for arcpath := range arcjoints {
+		var sp, err = jp.Sub(arcpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var fi fs.FileInfo
+		if fi, err = fs.Stat(sp, "."); err != nil {
+			t.Fatal(err)
+		}
+		if fi.(jnt.JointFileInfo).IsRealDir() {
+			t.Fatalf("%s: archive should be recognized as real file", arcpath)
+		}
+
+		// test FS at the end
+		if err = fstest.TestFS(sp, arcfiles...); err != nil {
+			t.Fatalf("%s: %v", arcpath, err)
+		}
+	}
+}
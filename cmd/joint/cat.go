@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// ParseRange parses bytes range in the same format as at HTTP Range
+// header without units: "start-end" with inclusive end, "start-" up to
+// the end of file, or "-n" for last n bytes. Returns offset and length,
+// negative length means up to the end of file.
+func ParseRange(s string, size int64) (offset, length int64, err error) {
+	var start, end, ok = strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid range %q", s)
+	}
+	if start == "" {
+		var n int64
+		if n, err = strconv.ParseInt(end, 10, 64); err != nil {
+			return
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, nil
+	}
+	if offset, err = strconv.ParseInt(start, 10, 64); err != nil {
+		return
+	}
+	if end == "" {
+		return offset, -1, nil
+	}
+	var last int64
+	if last, err = strconv.ParseInt(end, 10, 64); err != nil {
+		return
+	}
+	if last < offset {
+		return 0, 0, fmt.Errorf("invalid range %q", s)
+	}
+	return offset, last - offset + 1, nil
+}
+
+func runCat(ctx context.Context, jp *jnt.JointPool, args []string) (err error) {
+	var rng string
+	var fs = newflags("cat", "[flags] path...")
+	fs.StringVar(&rng, "r", "", `bytes range of files, "start-end", "start-" or "-last"`)
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("path should be given")
+	}
+	for _, arg := range fs.Args() {
+		if err = cat(jp, arg, rng); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// cat writes content of file, or given range of it. Errors have
+// path in form of given argument, without inserted credentials.
+func cat(jp *jnt.JointPool, arg, rng string) (err error) {
+	var f fs.File
+	if f, err = jp.Open(Resolve(arg)); err != nil {
+		return
+	}
+	defer f.Close()
+	var fi fs.FileInfo
+	if fi, err = f.Stat(); err != nil {
+		return
+	}
+	if fi.IsDir() {
+		return &fs.PathError{Op: "cat", Path: arg, Err: fs.ErrInvalid}
+	}
+	var r io.Reader = f
+	if rng != "" {
+		var offset, length int64
+		if offset, length, err = ParseRange(rng, fi.Size()); err != nil {
+			return
+		}
+		var s, ok = f.(io.Seeker)
+		if !ok {
+			return fmt.Errorf("file %s can not be read by range", arg)
+		}
+		if _, err = s.Seek(offset, io.SeekStart); err != nil {
+			return
+		}
+		if length >= 0 {
+			r = io.LimitReader(f, length)
+		}
+	}
+	_, err = io.Copy(stdout, r)
+	return
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// Credential is user name and password for some resource.
+type Credential struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// Settings is content of configuration file.
+type Settings struct {
+	// Settings of joints, it's loaded into jnt.Cfg.
+	Joint *jnt.Config `json:"joint,omitempty"`
+	// Credentials by resources addresses without user info,
+	// i.e. "ftp://192.168.1.1:21" or "https://example.com".
+	Credentials map[string]Credential `json:"credentials,omitempty"`
+}
+
+// cfg is loaded configuration.
+var cfg = Settings{
+	Joint: &jnt.Cfg,
+}
+
+// ConfigPath returns path to configuration file. It's given by
+// JOINT_CONFIG environment variable, or it's "joint/config.json"
+// at user's configuration directory.
+func ConfigPath() string {
+	if fpath := os.Getenv("JOINT_CONFIG"); fpath != "" {
+		return fpath
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "joint", "config.json")
+	}
+	return ""
+}
+
+// LoadConfig reads configuration file if it exists.
+func LoadConfig(fpath string) (err error) {
+	if fpath == "" {
+		return
+	}
+	var b []byte
+	if b, err = os.ReadFile(fpath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	return json.Unmarshal(b, &cfg)
+}
+
+// Resolve returns full path for joints pool. Credentials are inserted
+// into URL without user info from configuration, or from JOINT_USER
+// and JOINT_PASSWORD environment variables. Local paths are converted
+// to slash-separated.
+func Resolve(fpath string) string {
+	var i = strings.Index(fpath, "://")
+	if i == -1 {
+		return filepath.ToSlash(fpath)
+	}
+	var scheme, rest = fpath[:i], fpath[i+3:]
+	var host, tail = rest, ""
+	if j := strings.IndexByte(rest, '/'); j != -1 {
+		host, tail = rest[:j], rest[j:]
+	}
+	if strings.ContainsRune(host, '@') {
+		return fpath
+	}
+	var cred, ok = cfg.Credentials[scheme+"://"+host]
+	if !ok {
+		if cred.User = os.Getenv("JOINT_USER"); cred.User == "" {
+			return fpath
+		}
+		cred.Password = os.Getenv("JOINT_PASSWORD")
+	}
+	var user = url.UserPassword(cred.User, cred.Password)
+	if cred.Password == "" {
+		user = url.User(cred.User)
+	}
+	return scheme + "://" + user.String() + "@" + host + tail
+}
+
+// RedactText returns text, such as error message, where passwords
+// of all URLs are removed. Errors of pool have full paths with
+// credentials inserted by Resolve.
+func RedactText(s string) string {
+	var sb strings.Builder
+	for {
+		var i = strings.Index(s, "://")
+		if i == -1 {
+			break
+		}
+		s = jnt.Redact(s)
+		sb.WriteString(s[:i+3])
+		s = s[i+3:]
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
+
+// Unresolve returns found full path in the same form as given
+// argument, so credentials inserted by Resolve are not shown.
+func Unresolve(arg, fpath string) string {
+	var addr, _, isurl = jnt.SplitUrl(arg)
+	if !isurl {
+		return fpath
+	}
+	var resolved = Resolve(addr)
+	if strings.HasPrefix(fpath, resolved) {
+		return addr + fpath[len(resolved):]
+	}
+	return fpath
+}
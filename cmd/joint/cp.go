@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// target returns destination path for source file. If destination
+// is existing directory, file is placed into it with the same name.
+func target(jp *jnt.JointPool, src, dst string) string {
+	if fi, err := jp.Stat(dst); err == nil && isrealdir(fi) {
+		return jnt.JoinPath(dst, path.Base(src))
+	}
+	return dst
+}
+
+// progress returns callback that shows progress of file transfer at stderr.
+func progress(quiet bool) func(jnt.CopyProgress) {
+	if quiet {
+		return nil
+	}
+	var last string
+	var files int
+	return func(p jnt.CopyProgress) {
+		var line string
+		if p.Size > 0 {
+			line = fmt.Sprintf("%s %s / %s (%d%%)", path.Base(p.Src), humansize(p.Written), humansize(p.Size), p.Written*100/p.Size)
+		} else {
+			line = fmt.Sprintf("%s %s", path.Base(p.Src), humansize(p.Written))
+		}
+		if line != last {
+			fmt.Fprintf(os.Stderr, "\r%s\x1b[K", line)
+			last = line
+		}
+		if p.Files != files { // file is completed
+			fmt.Fprintln(os.Stderr)
+			files, last = p.Files, ""
+		}
+	}
+}
+
+func runCp(ctx context.Context, jp *jnt.JointPool, args []string) (err error) {
+	var opts jnt.CopyOptions
+	var force, skip, newer, quiet bool
+	var fs = newflags("cp", "[flags] src... dst")
+	fs.BoolVar(&opts.Recursive, "r", false, "copy directories recursively")
+	fs.BoolVar(&opts.PreserveMtime, "p", false, "preserve modification time")
+	fs.BoolVar(&force, "f", false, "rewrite existing files")
+	fs.BoolVar(&skip, "n", false, "do not rewrite existing files")
+	fs.BoolVar(&newer, "u", false, "rewrite existing files only if source is newer")
+	fs.BoolVar(&quiet, "q", false, "do not show progress")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return fmt.Errorf("source and destination should be given")
+	}
+	switch {
+	case force:
+		opts.Overwrite = jnt.OverwriteAlways
+	case skip:
+		opts.Overwrite = jnt.OverwriteSkip
+	case newer:
+		opts.Overwrite = jnt.OverwriteNewer
+	}
+	opts.Progress = progress(quiet)
+	var list = fs.Args()
+	var dst = Resolve(list[len(list)-1])
+	if len(list) > 2 {
+		if fi, err := jp.Stat(dst); err != nil || !isrealdir(fi) {
+			return fmt.Errorf("destination of several files should be directory")
+		}
+	}
+	for _, arg := range list[:len(list)-1] {
+		var src = Resolve(arg)
+		if err = jp.Copy(ctx, src, target(jp, src, dst), opts); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func runGet(ctx context.Context, jp *jnt.JointPool, args []string) (err error) {
+	var opts jnt.ResumeOptions
+	var quiet bool
+	var fs = newflags("get", "[flags] src [local]")
+	fs.StringVar(&opts.StateDir, "state", "", "directory with states of interrupted transfers")
+	fs.BoolVar(&quiet, "q", false, "do not show progress")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return fmt.Errorf("source should be given")
+	}
+	var src = Resolve(fs.Arg(0))
+	var dst = path.Base(src)
+	if fs.NArg() == 2 {
+		dst = fs.Arg(1)
+		if fi, err := os.Stat(dst); err == nil && fi.IsDir() {
+			dst = filepath.Join(dst, path.Base(src))
+		}
+	}
+	opts.Progress = progress(quiet)
+	return jp.Download(ctx, src, dst, opts)
+}
+
+func runPut(ctx context.Context, jp *jnt.JointPool, args []string) (err error) {
+	var opts jnt.ResumeOptions
+	var quiet bool
+	var fs = newflags("put", "[flags] local dst")
+	fs.StringVar(&opts.StateDir, "state", "", "directory with states of interrupted transfers")
+	fs.BoolVar(&quiet, "q", false, "do not show progress")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("source and destination should be given")
+	}
+	var src = fs.Arg(0)
+	opts.Progress = progress(quiet)
+	return jp.Upload(ctx, src, target(jp, filepath.ToSlash(src), Resolve(fs.Arg(1))), opts)
+}
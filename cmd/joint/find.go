@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+func runFind(ctx context.Context, jp *jnt.JointPool, args []string) (err error) {
+	var asjson, long bool
+	var opts jnt.FindOptions
+	var typ, after, before string
+	var fs = newflags("find", "[flags] pattern...")
+	fs.BoolVar(&asjson, "json", false, "JSON output")
+	fs.BoolVar(&long, "l", false, "long format with mode, size and modification time")
+	fs.BoolVar(&opts.Containers, "c", false, `descend into containers at "**" pattern chunk`)
+	fs.StringVar(&typ, "type", "", `type of found files, "f" for files or "d" for directories`)
+	fs.Int64Var(&opts.MinSize, "min", 0, "minimum size of found files")
+	fs.Int64Var(&opts.MaxSize, "max", 0, "maximum size of found files, 0 is unlimited")
+	fs.StringVar(&after, "newer", "", "find files modified after given time in RFC 3339 format or date")
+	fs.StringVar(&before, "older", "", "find files modified before given time in RFC 3339 format or date")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("pattern should be given")
+	}
+	switch typ {
+	case "":
+	case "f":
+		opts.Type = jnt.FindFiles
+	case "d":
+		opts.Type = jnt.FindDirs
+	default:
+		return fmt.Errorf("invalid type %q", typ)
+	}
+	if opts.After, err = parsetime(after); err != nil {
+		return
+	}
+	if opts.Before, err = parsetime(before); err != nil {
+		return
+	}
+
+	var ents = []*Entry{}
+	for _, arg := range fs.Args() {
+		var pattern = Resolve(arg)
+		var matches []string
+		if matches, err = jp.Find(pattern, opts); err != nil {
+			return
+		}
+		for _, fpath := range matches {
+			var fi, err = jp.Stat(fpath)
+			if err != nil {
+				return err
+			}
+			ents = append(ents, NewEntry(Unresolve(arg, fpath), fi))
+		}
+	}
+	if asjson {
+		return printjson(ents)
+	}
+	for _, ent := range ents {
+		if long {
+			fmt.Fprintf(stdout, "%s %12d %s %s\n", ent.Mode, ent.Size, ent.ModTime.Format("2006-01-02 15:04"), ent.Path)
+		} else {
+			fmt.Fprintln(stdout, ent.Path)
+		}
+	}
+	return
+}
+
+// parsetime parses time in RFC 3339 format or date in local time zone.
+func parsetime(s string) (t time.Time, err error) {
+	if s == "" {
+		return
+	}
+	if t, err = time.Parse(time.RFC3339, s); err == nil {
+		return
+	}
+	return time.ParseInLocation(time.DateOnly, s, time.Local)
+}
+
+func runDu(ctx context.Context, jp *jnt.JointPool, args []string) (err error) {
+	var asjson, human bool
+	var depth int
+	var opts jnt.WalkOptions
+	var exclude listflag
+	var fs = newflags("du", "[flags] path...")
+	fs.BoolVar(&asjson, "json", false, "JSON output")
+	fs.BoolVar(&human, "h", false, "print sizes in human readable format")
+	fs.BoolVar(&opts.Containers, "c", false, "descend into containers and count their content instead of files size")
+	fs.IntVar(&depth, "d", 0, "print totals of directories up to given depth")
+	fs.Var(&exclude, "exclude", "glob pattern of skipped files, can be given several times")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	var list = fs.Args()
+	if len(list) == 0 {
+		list = []string{"."}
+	}
+	opts.Exclude = exclude
+
+	type usage struct {
+		Path  string `json:"path"`
+		Size  int64  `json:"size"`
+		Files int    `json:"files"`
+	}
+	var result []usage
+	for _, arg := range list {
+		var base = strings.TrimSuffix(Resolve(arg), "/")
+		var totals = map[string]*usage{}
+		var add = func(rel string, size int64) {
+			var u, ok = totals[rel]
+			if !ok {
+				u = &usage{Path: jnt.JoinPath(arg, rel)}
+				totals[rel] = u
+			}
+			u.Size += size
+			u.Files++
+		}
+		totals[""] = &usage{Path: arg}
+		err = jp.Walk(ctx, base, opts, func(ent jnt.WalkEntry) error {
+			if ent.Err != nil {
+				return ent.Err
+			}
+			if ent.Depth == 0 {
+				return nil
+			}
+			var fi, err = ent.Info()
+			if err != nil {
+				return err
+			}
+			if fi.IsDir() && (opts.Containers || isrealdir(fi)) {
+				return nil
+			}
+			// count file at each parent directory up to given depth
+			var chunks = strings.Split(strings.TrimPrefix(ent.Path, base+"/"), "/")
+			chunks = chunks[:len(chunks)-1]
+			if len(chunks) > depth {
+				chunks = chunks[:depth]
+			}
+			for i := len(chunks); i >= 0; i-- {
+				add(strings.Join(chunks[:i], "/"), fi.Size())
+			}
+			return nil
+		})
+		if err != nil {
+			return
+		}
+		var keys = make([]string, 0, len(totals))
+		for rel := range totals {
+			keys = append(keys, rel)
+		}
+		sort.Strings(keys)
+		for i := len(keys) - 1; i >= 0; i-- { // nested directories first
+			result = append(result, *totals[keys[i]])
+		}
+	}
+	if asjson {
+		return printjson(result)
+	}
+	for _, u := range result {
+		if human {
+			fmt.Fprintf(stdout, "%8s %8d %s\n", humansize(u.Size), u.Files, u.Path)
+		} else {
+			fmt.Fprintf(stdout, "%12d %8d %s\n", u.Size, u.Files, u.Path)
+		}
+	}
+	return
+}
+
+// humansize returns size with binary prefix.
+func humansize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	var div, exp = int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(size)/float64(div), "KMGTPE"[exp])
+}
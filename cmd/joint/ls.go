@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+func runLs(ctx context.Context, jp *jnt.JointPool, args []string) (err error) {
+	var long, asjson bool
+	var fs = newflags("ls", "[flags] path...")
+	fs.BoolVar(&long, "l", false, "long format with mode, size and modification time")
+	fs.BoolVar(&asjson, "json", false, "JSON output")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	var list = fs.Args()
+	if len(list) == 0 {
+		list = []string{"."}
+	}
+	var result = map[string][]*Entry{}
+	for i, arg := range list {
+		var fpath = Resolve(arg)
+		var fi, err = jp.Stat(fpath)
+		if err != nil {
+			return err
+		}
+		var ents []*Entry
+		if fi.IsDir() {
+			var des, err = jp.ReadDir(fpath)
+			if err != nil {
+				return err
+			}
+			for _, de := range des {
+				var fi, err = de.Info()
+				if err != nil {
+					return err
+				}
+				ents = append(ents, NewEntry(jnt.JoinPath(arg, de.Name()), fi))
+			}
+		} else {
+			ents = append(ents, NewEntry(arg, fi))
+		}
+		if asjson {
+			result[arg] = ents
+			continue
+		}
+		if len(list) > 1 {
+			if i > 0 {
+				fmt.Fprintln(stdout)
+			}
+			fmt.Fprintf(stdout, "%s:\n", arg)
+		}
+		for _, ent := range ents {
+			var name = ent.Name
+			if ent.IsDir {
+				name += "/"
+			}
+			if long {
+				fmt.Fprintf(stdout, "%s %12d %s %s\n", ent.Mode, ent.Size, ent.ModTime.Format("2006-01-02 15:04"), name)
+			} else {
+				fmt.Fprintln(stdout, name)
+			}
+		}
+	}
+	if asjson {
+		if len(list) == 1 {
+			return printjson(result[list[0]])
+		}
+		return printjson(result)
+	}
+	return
+}
+
+func runStat(ctx context.Context, jp *jnt.JointPool, args []string) (err error) {
+	var asjson, nolink bool
+	var fs = newflags("stat", "[flags] path...")
+	fs.BoolVar(&asjson, "json", false, "JSON output")
+	fs.BoolVar(&nolink, "L", false, "do not follow symbolic links, describe containers as files")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("path should be given")
+	}
+	var ents []*Entry
+	for _, arg := range fs.Args() {
+		var fi, err = stat(jp, Resolve(arg), nolink)
+		if err != nil {
+			return err
+		}
+		ents = append(ents, NewEntry(arg, fi))
+	}
+	if asjson {
+		if len(ents) == 1 {
+			return printjson(ents[0])
+		}
+		return printjson(ents)
+	}
+	for _, ent := range ents {
+		var kind = "file"
+		if ent.IsRealDir {
+			kind = "directory"
+		} else if ent.IsDir {
+			kind = "container"
+		} else if strings.HasPrefix(ent.Mode, "L") {
+			kind = "symbolic link"
+		}
+		fmt.Fprintf(stdout, "  path: %s\n", ent.Path)
+		fmt.Fprintf(stdout, "  type: %s\n", kind)
+		fmt.Fprintf(stdout, "  size: %d\n", ent.Size)
+		fmt.Fprintf(stdout, "  mode: %s\n", ent.Mode)
+		fmt.Fprintf(stdout, "modify: %s\n", ent.ModTime.Format("2006-01-02 15:04:05 -0700"))
+		if ent.ETag != "" {
+			fmt.Fprintf(stdout, "  etag: %s\n", ent.ETag)
+		}
+	}
+	return
+}
+
+// stat returns file info with or without following of links.
+func stat(jp *jnt.JointPool, fpath string, nolink bool) (fs.FileInfo, error) {
+	if nolink {
+		return jp.Lstat(fpath)
+	}
+	return jp.Stat(fpath)
+}
+
+func runTree(ctx context.Context, jp *jnt.JointPool, args []string) (err error) {
+	var asjson bool
+	var opts jnt.WalkOptions
+	var exclude listflag
+	var fs = newflags("tree", "[flags] path")
+	fs.BoolVar(&asjson, "json", false, "JSON output")
+	fs.BoolVar(&opts.Containers, "c", false, "descend into containers")
+	fs.IntVar(&opts.MaxDepth, "L", 0, "maximum depth of tree, 0 is unlimited")
+	fs.Var(&exclude, "exclude", "glob pattern of skipped files, can be given several times")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("only one path can be given")
+	}
+	var arg = "."
+	if fs.NArg() == 1 {
+		arg = fs.Arg(0)
+	}
+	opts.Exclude = exclude
+
+	// build the tree, directories are walked in any order
+	var root *Entry
+	var dirs = map[string]*Entry{}
+	var base = strings.TrimSuffix(Resolve(arg), "/")
+	err = jp.Walk(ctx, base, opts, func(ent jnt.WalkEntry) error {
+		if ent.Err != nil {
+			return ent.Err
+		}
+		var fi, err = ent.Info()
+		if err != nil {
+			return err
+		}
+		var rel = strings.TrimPrefix(strings.TrimPrefix(ent.Path, base), "/")
+		var e = NewEntry(jnt.JoinPath(arg, rel), fi)
+		if ent.Depth == 0 {
+			root = e
+		} else {
+			var parent = dirs[path.Dir(ent.Path)]
+			parent.Children = append(parent.Children, e)
+		}
+		if fi.IsDir() {
+			dirs[ent.Path] = e
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	var sortent func(e *Entry)
+	sortent = func(e *Entry) {
+		sort.Slice(e.Children, func(i, j int) bool { return e.Children[i].Name < e.Children[j].Name })
+		for _, c := range e.Children {
+			sortent(c)
+		}
+	}
+	sortent(root)
+	if asjson {
+		return printjson(root)
+	}
+
+	var ndirs, nfiles int
+	var printtree func(e *Entry, prefix string)
+	printtree = func(e *Entry, prefix string) {
+		for i, c := range e.Children {
+			var name = c.Name
+			if c.IsDir {
+				name += "/"
+			}
+			if i == len(e.Children)-1 {
+				fmt.Fprintf(stdout, "%s└── %s\n", prefix, name)
+				printtree(c, prefix+"    ")
+			} else {
+				fmt.Fprintf(stdout, "%s├── %s\n", prefix, name)
+				printtree(c, prefix+"│   ")
+			}
+			if c.IsRealDir {
+				ndirs++
+			} else {
+				nfiles++
+			}
+		}
+	}
+	fmt.Fprintln(stdout, arg)
+	printtree(root, "")
+	fmt.Fprintf(stdout, "\n%d directories, %d files\n", ndirs, nfiles)
+	return
+}
@@ -0,0 +1,42 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+func init() {
+	commands = append(commands, command{"mount", "mount path as FUSE file system", runMount})
+}
+
+func runMount(ctx context.Context, jp *jnt.JointPool, args []string) (err error) {
+	var opts jnt.MountOptions
+	var fs = newflags("mount", "[flags] path mountpoint")
+	fs.BoolVar(&opts.Writable, "rw", false, "mount with write access")
+	fs.BoolVar(&opts.AllowOther, "allow-other", false, "allow access to other users")
+	fs.DurationVar(&opts.AttrTimeout, "timeout", jnt.DefMountTimeout, "time while files attributes are cached, negative disables caching")
+	fs.BoolVar(&opts.Debug, "debug", false, "print FUSE requests")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("path and mount point should be given")
+	}
+	var sp = jnt.NewSubPool(jp, Resolve(fs.Arg(0)))
+	var srv, err1 = jnt.Mount(sp, fs.Arg(1), opts)
+	if err1 != nil {
+		return err1
+	}
+	// unmount on interrupt, or wait until unmounted by fusermount -u
+	go func() {
+		<-ctx.Done()
+		srv.Unmount()
+	}()
+	srv.Wait()
+	return
+}
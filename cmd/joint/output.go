@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// stdout is output of commands.
+var stdout io.Writer = os.Stdout
+
+// Entry is file description for JSON output.
+type Entry struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path,omitempty"`
+	Size      int64     `json:"size"`
+	Mode      string    `json:"mode"`
+	ModTime   time.Time `json:"mtime"`
+	IsDir     bool      `json:"dir"`
+	IsRealDir bool      `json:"realdir"`
+	ETag      string    `json:"etag,omitempty"`
+	Children  []*Entry  `json:"children,omitempty"`
+}
+
+// isrealdir returns true if file info describes real directory.
+func isrealdir(fi fs.FileInfo) bool {
+	if jfi, ok := fi.(jnt.FileInfo); ok {
+		return jfi.IsRealDir()
+	}
+	return fi.IsDir()
+}
+
+// NewEntry makes entry for given file info.
+func NewEntry(fpath string, fi fs.FileInfo) *Entry {
+	return &Entry{
+		Name:      fi.Name(),
+		Path:      fpath,
+		Size:      fi.Size(),
+		Mode:      fi.Mode().String(),
+		ModTime:   fi.ModTime(),
+		IsDir:     fi.IsDir(),
+		IsRealDir: isrealdir(fi),
+		ETag:      jnt.FileETag(fi),
+	}
+}
+
+// printjson writes given value as indented JSON.
+func printjson(v any) error {
+	var enc = json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
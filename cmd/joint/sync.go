@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+func runSync(ctx context.Context, jp *jnt.JointPool, args []string) (err error) {
+	var opts jnt.SyncOptions
+	var exclude listflag
+	var quiet bool
+	var fs = newflags("sync", "[flags] src dst")
+	fs.BoolVar(&opts.Delete, "delete", false, "delete extraneous files at destination")
+	fs.BoolVar(&opts.DryRun, "n", false, "dry run, show operations without changes")
+	fs.Var(&exclude, "exclude", "glob pattern of skipped files, can be given several times")
+	fs.BoolVar(&opts.Checksum, "checksum", false, "compare files with the same size by SHA-256 hash")
+	fs.DurationVar(&opts.ModifyWindow, "window", 0, "maximum difference of modification times of the same files")
+	fs.IntVar(&opts.Joints, "j", jnt.DefWalkJoints, "number of concurrent transfers at each resource")
+	fs.BoolVar(&quiet, "q", false, "do not print operations")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("source and destination should be given")
+	}
+	opts.Exclude = exclude
+	opts.Report = func(act jnt.SyncAction) {
+		if act.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s %s: %s\n", act.Op, act.Dst, RedactText(act.Err.Error()))
+		} else if !quiet {
+			fmt.Printf("%s %s\n", act.Op, act.Dst)
+		}
+	}
+
+	var res jnt.SyncResult
+	res, err = jp.Sync(ctx, fs.Arg(0), fs.Arg(1), opts)
+	if !quiet {
+		fmt.Printf("copied %d, updated %d, directories %d, deleted %d, skipped %d, transferred %d bytes\n",
+			res.Copied, res.Updated, res.Dirs, res.Deleted, res.Skipped, res.Bytes)
+	}
+	return
+}
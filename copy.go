@@ -0,0 +1,518 @@
+package joint
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCopyDir  = errors.New("source is directory, and recursive copy is not set")
+	ErrCopySelf = errors.New("destination is the source or placed inside of source directory")
+	ErrWriteGap = errors.New("file is written with gaps")
+)
+
+// OverwritePolicy determines how Copy treats existing destination files.
+type OverwritePolicy int
+
+const (
+	OverwriteError  OverwritePolicy = iota // existing file breaks copying with fs.ErrExist error
+	OverwriteSkip                          // existing files are left as is
+	OverwriteAlways                        // existing files are rewritten
+	OverwriteNewer                         // existing files are rewritten if source file is newer
+)
+
+// CopyProgress describes the state of copying passed to progress callback.
+type CopyProgress struct {
+	Src, Dst string // full paths of currently copied file
+	Size     int64  // size of currently copied file
+	Written  int64  // number of bytes written to currently copied file
+	Files    int    // number of copied files, skipped files are not counted
+	Bytes    int64  // number of bytes written to all files
+}
+
+// CopyOptions determines how Copy works.
+type CopyOptions struct {
+	// Policy for existing destination files.
+	Overwrite OverwritePolicy
+	// Copy directories with all nested content. Containers such as
+	// ISO-images and archives are copied as files.
+	Recursive bool
+	// Set modification time of copied files and directories the same
+	// as at source, if destination joint supports it.
+	PreserveMtime bool
+	// Callback called after each written chunk of file, and after
+	// each copied file. It's never called concurrently.
+	Progress func(CopyProgress)
+}
+
+// copier keeps state of copying.
+type copier struct {
+	jp   *JointPool
+	ctx  context.Context
+	opts *CopyOptions
+	prog CopyProgress
+}
+
+// progress reports current state to callback.
+func (c *copier) progress() {
+	if c.opts.Progress != nil {
+		c.opts.Progress(c.prog)
+	}
+}
+
+// copyreader counts read bytes, reports progress and breaks
+// reading on context cancel.
+type copyreader struct {
+	r io.Reader
+	c *copier
+}
+
+func (cr copyreader) Read(b []byte) (n int, err error) {
+	if err = cr.c.ctx.Err(); err != nil {
+		return
+	}
+	n, err = cr.r.Read(b)
+	if n > 0 {
+		cr.c.prog.Written += int64(n)
+		cr.c.prog.Bytes += int64(n)
+		cr.c.progress()
+	}
+	return
+}
+
+// chtimes sets modification time of destination file if it's supported.
+func (c *copier) chtimes(dst string, fi fs.FileInfo) (err error) {
+	if !c.opts.PreserveMtime {
+		return
+	}
+	var dw, dpath, err1 = c.jp.getjoint(dst, len(dst)-1)
+	if err1 != nil {
+		return err1
+	}
+	defer func() { release(dw, err) }()
+	if tj, ok := dw.Joint.(ChtimesJoint); ok {
+		if err = tj.Chtimes(dpath, fi.ModTime()); errors.Is(err, errors.ErrUnsupported) {
+			err = nil
+		}
+	}
+	return
+}
+
+// copyfile copies single file, containers are copied as files.
+func (c *copier) copyfile(src, dst string, fi fs.FileInfo) (err error) {
+	if err = c.ctx.Err(); err != nil {
+		return
+	}
+	var dfi fs.FileInfo
+	if dfi, err = c.jp.Lstat(dst); err == nil {
+		switch c.opts.Overwrite {
+		case OverwriteError:
+			return &fs.PathError{Op: "copy", Path: dst, Err: fs.ErrExist}
+		case OverwriteSkip:
+			return nil
+		case OverwriteNewer:
+			if !fi.ModTime().After(dfi.ModTime()) {
+				return nil
+			}
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+
+	var dw, dpath, err1 = c.jp.getjoint(dst, len(dst)-1)
+	if err1 != nil {
+		return err1
+	}
+	defer func() { release(dw, err) }()
+	var wj, ok = dw.Joint.(WriteJoint)
+	if !ok {
+		return &fs.PathError{Op: "copy", Path: dst, Err: ErrReadOnly}
+	}
+	var sw, spath, err2 = c.jp.getjoint(src, len(src)-1)
+	if err2 != nil {
+		return err2
+	}
+	defer func() { release(sw, err) }()
+
+	c.prog.Src, c.prog.Dst = src, dst
+	c.prog.Size, c.prog.Written = fi.Size(), 0
+	var copied bool
+	if cj, ok := dw.Joint.(CopyJoint); ok && dw.jc != nil && dw.jc == sw.jc {
+		if cj.CopyFile(spath, dpath) == nil { // stream the file on any error
+			c.prog.Written = fi.Size()
+			c.prog.Bytes += fi.Size()
+			copied = true
+		}
+	}
+	if !copied {
+		if _, err = sw.Open(spath); err != nil {
+			return
+		}
+		if err = wj.Put(dpath, copyreader{sw.Joint, c}); err != nil {
+			return
+		}
+	}
+	c.prog.Files++
+	c.progress()
+	return c.chtimes(dst, fi)
+}
+
+// copydir copies directory with all nested content.
+func (c *copier) copydir(src, dst string, fi fs.FileInfo) (err error) {
+	if err = c.ctx.Err(); err != nil {
+		return
+	}
+	var dfi fs.FileInfo
+	if dfi, err = c.jp.Stat(dst); err == nil {
+		if !dfi.IsDir() {
+			return &fs.PathError{Op: "copy", Path: dst, Err: fs.ErrExist}
+		}
+	} else if errors.Is(err, fs.ErrNotExist) {
+		if err = c.jp.Mkdir(dst); err != nil {
+			return
+		}
+	} else {
+		return
+	}
+
+	var list []fs.DirEntry
+	if list, err = c.jp.ReadDirContext(c.ctx, src); err != nil {
+		return
+	}
+	for _, de := range list {
+		var sfpath, dfpath = JoinPath(src, de.Name()), JoinPath(dst, de.Name())
+		var sfi fs.FileInfo
+		if sfi, err = de.Info(); err != nil {
+			return
+		}
+		if isrealdir(de) {
+			err = c.copydir(sfpath, dfpath, sfi)
+		} else {
+			err = c.copyfile(sfpath, dfpath, sfi)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return c.chtimes(dst, fi)
+}
+
+// write calls given function for writable joint at given full path.
+func (jp *JointPool) write(op, fullpath string, fn func(wj WriteJoint, fpath string) error) (err error) {
+	fullpath = strings.TrimSuffix(fullpath, "/")
+	var jw, fpath, err1 = jp.getjoint(fullpath, len(fullpath)-1)
+	if err1 != nil {
+		return err1
+	}
+	defer func() { release(jw, err) }()
+	var wj, ok = jw.Joint.(WriteJoint)
+	if !ok {
+		return &fs.PathError{Op: op, Path: fullpath, Err: ErrReadOnly}
+	}
+	return fn(wj, fpath)
+}
+
+// putwriter streams written content to the joint in background.
+type putwriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (w *putwriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *putwriter) Close() error {
+	if w.pw == nil {
+		return nil
+	}
+	w.pw.Close()
+	w.pw = nil
+	return <-w.done
+}
+
+// jointreader serializes concurrent reading of opened joint,
+// and releases the joint on close.
+type jointreader struct {
+	jw  JointWrap
+	mux sync.Mutex
+}
+
+func (r *jointreader) ReadAt(b []byte, off int64) (int, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.jw.ReadAt(b, off)
+}
+
+func (r *jointreader) Close() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	release(r.jw, nil)
+	return nil
+}
+
+// SeqPendingLimit is maximum size in bytes of chunks received out of
+// order, which are kept by writers of SFTP-server and FUSE until preceding
+// chunks are written. Writing beyond the limit fails with ErrWriteGap.
+const SeqPendingLimit = 16 * 1024 * 1024
+
+// seqwriter streams content of file written by chunks at given offsets,
+// i.e. by SFTP-clients or FUSE. Chunks can be sent concurrently, so
+// chunks received out of order are kept until preceding chunks are
+// written. Size of kept chunks is limited by SeqPendingLimit.
+type seqwriter struct {
+	wc      io.WriteCloser
+	off     int64
+	pending map[int64][]byte
+	size    int // size of pending chunks
+	err     error
+	closed  bool
+	mux     sync.Mutex
+}
+
+func (w *seqwriter) WriteAt(p []byte, off int64) (n int, err error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	if off != w.off {
+		if off < w.off {
+			return 0, ErrWriteGap
+		}
+		if w.pending == nil {
+			w.pending = map[int64][]byte{}
+		}
+		var size = w.size + len(p) - len(w.pending[off])
+		if size > SeqPendingLimit {
+			w.err = ErrWriteGap
+			return 0, w.err
+		}
+		w.pending[off] = append([]byte(nil), p...)
+		w.size = size
+		return len(p), nil
+	}
+	if n, err = w.wc.Write(p); err != nil {
+		w.err = err
+		return
+	}
+	w.off += int64(n)
+	for {
+		var b, ok = w.pending[w.off]
+		if !ok {
+			break
+		}
+		delete(w.pending, w.off)
+		w.size -= len(b)
+		if _, w.err = w.wc.Write(b); w.err != nil {
+			break
+		}
+		w.off += int64(len(b))
+	}
+	return
+}
+
+func (w *seqwriter) Close() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.wc == nil { // nothing was written
+		return nil
+	}
+	var err = w.wc.Close()
+	if w.err == nil && len(w.pending) > 0 {
+		w.err = ErrWriteGap
+	}
+	w.err = errors.Join(w.err, err)
+	return w.err
+}
+
+// Create creates or rewrites file pointed by given full path, and
+// returns writer that streams content to writable joint. File is
+// completed on Close, and Close returns the error of writing.
+// Files inside of containers can not be created.
+func (jp *JointPool) Create(fullpath string) (io.WriteCloser, error) {
+	return jp.create(fullpath, 0)
+}
+
+// create returns writer to file from given offset, offset
+// can be non-zero only for joints that can resume writing.
+func (jp *JointPool) create(fullpath string, offset int64) (io.WriteCloser, error) {
+	var jw, fpath, err = jp.getjoint(fullpath, len(fullpath)-1)
+	if err != nil {
+		return nil, err
+	}
+	var put func(io.Reader) error
+	if offset > 0 {
+		var rj, ok = jw.Joint.(ResumeJoint)
+		if !ok {
+			release(jw, nil)
+			return nil, &fs.PathError{Op: "create", Path: fullpath, Err: errors.ErrUnsupported}
+		}
+		put = func(r io.Reader) error { return rj.PutFrom(fpath, r, offset) }
+	} else {
+		var wj, ok = jw.Joint.(WriteJoint)
+		if !ok {
+			release(jw, nil)
+			return nil, &fs.PathError{Op: "create", Path: fullpath, Err: ErrReadOnly}
+		}
+		put = func(r io.Reader) error { return wj.Put(fpath, r) }
+	}
+	var pr, pw = io.Pipe()
+	var w = &putwriter{
+		pw:   pw,
+		done: make(chan error, 1),
+	}
+	go func() {
+		var err = put(pr)
+		pr.CloseWithError(err)
+		release(jw, err)
+		w.done <- err
+	}()
+	return w, nil
+}
+
+// Chtimes changes modification time of file pointed by given full path,
+// returns errors.ErrUnsupported if joint can not do it.
+func (jp *JointPool) Chtimes(fullpath string, mtime time.Time) error {
+	return jp.write("chtimes", fullpath, func(wj WriteJoint, fpath string) error {
+		if tj, ok := wj.(ChtimesJoint); ok {
+			return tj.Chtimes(fpath, mtime)
+		}
+		return errors.ErrUnsupported
+	})
+}
+
+// Mkdir creates directory pointed by given full path.
+// Parent directory should exist.
+func (jp *JointPool) Mkdir(fullpath string) error {
+	return jp.write("mkdir", fullpath, func(wj WriteJoint, fpath string) error {
+		return wj.Mkdir(fpath)
+	})
+}
+
+// Remove removes file or empty directory pointed by given full path.
+// Containers are removed as files.
+func (jp *JointPool) Remove(fullpath string) error {
+	return jp.write("remove", fullpath, func(wj WriteJoint, fpath string) error {
+		return wj.Remove(fpath)
+	})
+}
+
+// RemoveAll removes file or directory pointed by given full path
+// with all nested content. Containers are removed as files.
+func (jp *JointPool) RemoveAll(fullpath string) (err error) {
+	var fi fs.FileInfo
+	if fi, err = jp.Lstat(fullpath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	if isrealdir(ToDirEntry(fi)) {
+		var list []fs.DirEntry
+		if list, err = jp.ReadDir(fullpath); err != nil {
+			return
+		}
+		for _, de := range list {
+			if err = jp.RemoveAll(JoinPath(fullpath, de.Name())); err != nil {
+				return
+			}
+		}
+	}
+	return jp.Remove(fullpath)
+}
+
+// Rename moves file or directory to new full path. If both paths are
+// placed at the same joint, it's moved by joint, otherwise it's copied
+// with preserving of modification time, and then source is removed.
+func (jp *JointPool) Rename(oldpath, newpath string) (err error) {
+	oldpath, newpath = strings.TrimSuffix(oldpath, "/"), strings.TrimSuffix(newpath, "/")
+	var ow, opath, err1 = jp.getjoint(oldpath, len(oldpath)-1)
+	if err1 != nil {
+		return err1
+	}
+	var nw, npath, err2 = jp.getjoint(newpath, len(newpath)-1)
+	if err2 != nil {
+		release(ow, nil)
+		return err2
+	}
+	// joints without cache are joints to local file system
+	if rj, ok := nw.Joint.(RenameJoint); ok && nw.jc == ow.jc {
+		err = rj.Rename(opath, npath)
+		release(ow, err)
+		release(nw, err)
+		return
+	}
+	release(ow, nil)
+	release(nw, nil)
+
+	if err = jp.Copy(context.Background(), oldpath, newpath, CopyOptions{
+		Overwrite:     OverwriteAlways,
+		Recursive:     true,
+		PreserveMtime: true,
+	}); err != nil {
+		return
+	}
+	return jp.RemoveAll(oldpath)
+}
+
+// Copy copies file or directory given by full path to destination
+// full path, that can be located at another joint. If both paths are
+// placed at the same server, and joint supports it, files are copied
+// at server side, i.e. by COPY method at WebDAV-server. Otherwise
+// content is streamed from source joint to destination joint. SFTP
+// client does not send "copy-data" extension request, so files at
+// SFTP-servers are streamed too.
+// Destination should be at writable file system, not in container.
+// Parent directory of destination should exist.
+func (jp *JointPool) Copy(ctx context.Context, src, dst string, opts CopyOptions) (err error) {
+	if len(src) > 1 {
+		src = strings.TrimSuffix(src, "/")
+	}
+	if len(dst) > 1 {
+		dst = strings.TrimSuffix(dst, "/")
+	}
+	var fi fs.FileInfo
+	if fi, err = jp.StatContext(ctx, src); err != nil {
+		return
+	}
+	var c = copier{
+		jp:   jp,
+		ctx:  ctx,
+		opts: &opts,
+	}
+	if dst == src { // rewrite of destination truncates source
+		return &fs.PathError{Op: "copy", Path: dst, Err: ErrCopySelf}
+	}
+	if !isrealdir(ToDirEntry(fi)) {
+		return c.copyfile(src, dst, fi)
+	}
+	if !opts.Recursive {
+		return &fs.PathError{Op: "copy", Path: src, Err: ErrCopyDir}
+	}
+	if strings.HasPrefix(dst, JoinPath(src, "/")) {
+		return &fs.PathError{Op: "copy", Path: dst, Err: ErrCopySelf}
+	}
+	return c.copydir(src, dst, fi)
+}
+
+// Copy is the same as JointPool.Copy, but receives paths relative
+// to subsystem root.
+func (sp *SubPool) Copy(ctx context.Context, src, dst string, opts CopyOptions) error {
+	if sp.dir != "" && sp.dir != "." && (!fs.ValidPath(src) || !fs.ValidPath(dst)) {
+		return fs.ErrInvalid
+	}
+	return sp.JointPool.Copy(ctx, JoinPath(sp.dir, src), JoinPath(sp.dir, dst), opts)
+}
@@ -0,0 +1,150 @@
+package joint_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+func TestCopyFile(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+	var root = filepath.ToSlash(t.TempDir())
+	var ctx = context.Background()
+
+	var calls int
+	var last jnt.CopyProgress
+	var opts = jnt.CopyOptions{
+		Progress: func(p jnt.CopyProgress) {
+			calls++
+			last = p
+		},
+	}
+	var dst = root + "/fox.txt"
+	if err := jp.Copy(ctx, "testdata/external.iso/disk/internal.iso/fox.txt", dst, opts); err != nil {
+		t.Fatal(err)
+	}
+	if calls == 0 || last.Files != 1 || last.Written != foxsize || last.Bytes != foxsize {
+		t.Fatal("progress does not match")
+	}
+	var b, err = os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != foxsize {
+		t.Fatal("size of copied file does not match")
+	}
+
+	// existing file
+	if err = jp.Copy(ctx, "testdata/archive.zip/fox.txt", dst, opts); !errors.Is(err, fs.ErrExist) {
+		t.Fatal("existing file should not be rewritten by default")
+	}
+	opts.Overwrite = jnt.OverwriteSkip
+	if err = jp.Copy(ctx, "testdata/archive.zip/fox.txt", dst, opts); err != nil {
+		t.Fatal(err)
+	}
+	if last.Files != 1 {
+		t.Fatal("skipped file should not be copied")
+	}
+
+	// container is copied as file
+	var fi fs.FileInfo
+	if fi, err = os.Stat("testdata/archive.zip"); err != nil {
+		t.Fatal(err)
+	}
+	opts.PreserveMtime = true
+	if err = jp.Copy(ctx, "testdata/archive.zip", root+"/archive.zip", opts); err != nil {
+		t.Fatal(err)
+	}
+	var cfi fs.FileInfo
+	if cfi, err = os.Stat(root + "/archive.zip"); err != nil {
+		t.Fatal(err)
+	}
+	if cfi.Size() != fi.Size() || !cfi.ModTime().Equal(fi.ModTime()) {
+		t.Fatal("copied container does not match to source")
+	}
+	if _, err = jp.Stat(root + "/archive.zip/data/docs/doc1.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// file is not copied onto itself
+	opts.Overwrite = jnt.OverwriteAlways
+	if err = jp.Copy(ctx, dst, dst+"/", opts); !errors.Is(err, jnt.ErrCopySelf) {
+		t.Fatal("file should not be copied onto itself")
+	}
+	if b, err = os.ReadFile(dst); err != nil || len(b) != foxsize {
+		t.Fatal("source file is damaged by copying onto itself")
+	}
+
+	// destination inside of container
+	if err = jp.Copy(ctx, dst, root+"/archive.zip/fox2.txt", opts); !errors.Is(err, jnt.ErrReadOnly) {
+		t.Fatal("container should not be writable")
+	}
+}
+
+func TestCopyDir(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+	var root = filepath.ToSlash(t.TempDir())
+	var ctx = context.Background()
+
+	var src = "testdata/external.iso/data"
+	if err := jp.Copy(ctx, src, root+"/data", jnt.CopyOptions{}); !errors.Is(err, jnt.ErrCopyDir) {
+		t.Fatal("directory should not be copied without recursive option")
+	}
+	var opts = jnt.CopyOptions{
+		Recursive:     true,
+		PreserveMtime: true,
+	}
+	if err := jp.Copy(ctx, src, src+"/docs", opts); !errors.Is(err, jnt.ErrCopySelf) {
+		t.Fatal("directory should not be copied into itself")
+	}
+	if err := jp.Copy(ctx, src, root+"/data", opts); err != nil {
+		t.Fatal(err)
+	}
+	var err = fs.WalkDir(jp, src, func(fpath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		var fi, cfi fs.FileInfo
+		if fi, err = d.Info(); err != nil {
+			return err
+		}
+		if cfi, err = os.Stat(root + "/data" + fpath[len(src):]); err != nil {
+			return err
+		}
+		if cfi.IsDir() != fi.IsDir() || (!fi.IsDir() && cfi.Size() != fi.Size()) {
+			t.Fatalf("%s: copied file does not match", fpath)
+		}
+		if !fi.ModTime().IsZero() && !cfi.ModTime().Equal(fi.ModTime().Truncate(time.Second)) &&
+			!cfi.ModTime().Equal(fi.ModTime()) {
+			t.Fatalf("%s: modification time does not match", fpath)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// merge with existing directory
+	var ctx2, cancel = context.WithCancel(ctx)
+	cancel()
+	if err = jp.Copy(ctx2, src, root+"/data", opts); !errors.Is(err, context.Canceled) {
+		t.Fatal("copying should be broken by context")
+	}
+	opts.Overwrite = jnt.OverwriteNewer
+	var files int
+	opts.Progress = func(p jnt.CopyProgress) { files = p.Files }
+	if err = jp.Copy(ctx, src, root+"/data", opts); err != nil {
+		t.Fatal(err)
+	}
+	if files != 0 {
+		t.Fatal("files with the same time should not be rewritten")
+	}
+}
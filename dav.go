@@ -1,10 +1,15 @@
 package joint
 
 import (
+	"errors"
 	"io"
 	"io/fs"
+	"net"
+	"net/http"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/studio-b12/gowebdav"
 )
@@ -14,6 +19,10 @@ type DavFileInfo = gowebdav.File
 // davroot is global map of WebDAV servises root paths by services URLs.
 // External links are external in all cases, so this object is singleton.
 var davroot = map[string]string{}
+
+// nodav is set of URLs of paths where WebDAV root was not found,
+// with time of probe.
+var nodav = map[string]time.Time{}
 var davmux sync.RWMutex
 
 // GetDavRoot returns cached WebDAV root for given address.
@@ -28,15 +37,76 @@ func GetDavRoot(addr string) (root string, ok bool) {
 func SetDavRoot(addr, root string) {
 	davmux.Lock()
 	davroot[addr] = root
+	for key := range nodav {
+		if strings.HasPrefix(key, addr+"/") {
+			delete(nodav, key)
+		}
+	}
 	davmux.Unlock()
 }
 
+// nodavkey returns key of path at nodav set.
+func nodavkey(addr, fpath string) string {
+	if fpath == "" || strings.HasSuffix(fpath, "/") {
+		return addr + "/" + fpath
+	}
+	return addr + "/" + fpath + "/"
+}
+
+// isnodav checks whether path is placed at probed path
+// without WebDAV root, and probe is not expired.
+func isnodav(addr, fpath string) bool {
+	var key = nodavkey(addr, fpath)
+	var now = time.Now()
+	davmux.RLock()
+	defer davmux.RUnlock()
+	for k, t := range nodav {
+		if strings.HasPrefix(key, k) && now.Sub(t) < Cfg.DavProbeExpire {
+			return true
+		}
+	}
+	return false
+}
+
+// setnodav remembers path where WebDAV root was not found,
+// and removes expired probes.
+func setnodav(addr, fpath string) {
+	var now = time.Now()
+	davmux.Lock()
+	defer davmux.Unlock()
+	for k, t := range nodav {
+		if now.Sub(t) >= Cfg.DavProbeExpire {
+			delete(nodav, k)
+		}
+	}
+	nodav[nodavkey(addr, fpath)] = now
+}
+
+// isdavfail checks whether error of probe is failure of transport
+// or of server, so probe result can not be remembered.
+func isdavfail(err error) bool {
+	var se gowebdav.StatusError
+	if errors.As(err, &se) {
+		return se.Status >= 500 && se.Status != http.StatusNotImplemented
+	}
+	var ne net.Error
+	return errors.As(err, &ne) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
 // FindDavRoot returns cached root for given address,
-// or tries to find it from given path.
+// or tries to find it from given path. If root is not found,
+// path is remembered as placed at service without WebDAV support,
+// and paths inside of it are not probed again until Cfg.DavProbeExpire
+// is passed. Failures of network or of server are not remembered.
 func FindDavRoot(addr, fpath string) (root string, ok bool) {
-	if root, ok = GetDavRoot(addr); ok {
+	davmux.RLock()
+	root, ok = davroot[addr]
+	davmux.RUnlock()
+	if ok || isnodav(addr, fpath) {
 		return
 	}
+	var probe = fpath
+	var fail bool       // some probe was failed
 	fpath = "/" + fpath // check up empty root
 	var pos int
 	for {
@@ -47,18 +117,24 @@ func FindDavRoot(addr, fpath string) (root string, ok bool) {
 			root = fpath
 		}
 		var client = gowebdav.NewClient(addr+root, "", "")
-		if fi, err := client.Stat(""); err == nil && fi.IsDir() {
+		var fi, err = client.Stat("")
+		if err == nil && fi.IsDir() {
 			SetDavRoot(addr, root)
 			ok = true
 			return
 		}
-		if i != -1 {
-			pos = i + 1
-		} else {
+		if isdavfail(err) {
+			fail = true
+		}
+		if i == -1 {
 			break
 		}
+		pos += i + 1
 	}
-	return
+	if !fail {
+		setnodav(addr, probe)
+	}
+	return "", false
 }
 
 // DavJoint keeps gowebdav.Client object.
@@ -211,3 +287,43 @@ func (j *DavJoint) Info(fpath string) (fs.FileInfo, error) {
 	var fi, err = j.client.Stat(fpath)
 	return ToFileInfo(fi), err
 }
+
+// Put creates or rewrites file at WebDAV-server with content of given reader.
+func (j *DavJoint) Put(fpath string, r io.Reader) error {
+	return j.client.WriteStream(fpath, r, 0644)
+}
+
+// Mkdir creates new directory at WebDAV-server.
+func (j *DavJoint) Mkdir(fpath string) error {
+	return j.client.Mkdir(fpath, 0755)
+}
+
+// Remove removes file or empty directory at WebDAV-server. DELETE
+// method removes directories with all content, so directory is
+// checked to be empty before.
+func (j *DavJoint) Remove(fpath string) (err error) {
+	var fi fs.FileInfo
+	if fi, err = j.client.Stat(fpath); err != nil {
+		return
+	}
+	if fi.IsDir() {
+		var files []fs.FileInfo
+		if files, err = j.client.ReadDir(fpath); err != nil {
+			return
+		}
+		if len(files) > 0 {
+			return &fs.PathError{Op: "remove", Path: fpath, Err: syscall.ENOTEMPTY}
+		}
+	}
+	return j.client.Remove(fpath)
+}
+
+// Rename moves file or directory at WebDAV-server by MOVE method.
+func (j *DavJoint) Rename(oldpath, newpath string) error {
+	return j.client.Rename(oldpath, newpath, true)
+}
+
+// CopyFile copies file at server side by COPY method.
+func (j *DavJoint) CopyFile(src, dst string) error {
+	return j.client.Copy(src, dst, true)
+}
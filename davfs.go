@@ -0,0 +1,276 @@
+package joint
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+// DavFS implements webdav.FileSystem interface over SubPool, so content
+// of any joints, i.e. ISO-images, FTP, SFTP and S3 resources, can be
+// shared by WebDAV-server. Containers are represented as collections.
+// Files can be created, rewritten, moved and removed if they are placed
+// at writable joints, but partial writing of files is not supported.
+type DavFS struct {
+	*SubPool
+}
+
+// NewDavFS returns WebDAV file system for given subsystem.
+func NewDavFS(sp *SubPool) *DavFS {
+	return &DavFS{sp}
+}
+
+// Mkdir implements webdav.FileSystem interface.
+func (dfs *DavFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	var fullpath, err = dfs.fullpath(name)
+	if err != nil {
+		return err
+	}
+	return dfs.JointPool.Mkdir(fullpath)
+}
+
+// OpenFile implements webdav.FileSystem interface. Files opened for
+// writing are always truncated.
+func (dfs *DavFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	var fullpath, err = dfs.fullpath(name)
+	if err != nil {
+		return nil, err
+	}
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) == 0 {
+		var f fs.File
+		if f, err = dfs.JointPool.OpenContext(ctx, fullpath); err != nil {
+			return nil, err
+		}
+		return &davfile{f}, nil
+	}
+
+	var fi fs.FileInfo
+	if fi, err = dfs.JointPool.Lstat(fullpath); err == nil {
+		if flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+		}
+		if isrealdir(ToDirEntry(fi)) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) || flag&os.O_CREATE == 0 {
+		return nil, err
+	}
+	if flag&os.O_TRUNC == 0 && fi != nil && fi.Size() > 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.ErrUnsupported}
+	}
+
+	var wc io.WriteCloser
+	if wc, err = dfs.JointPool.Create(fullpath); err != nil {
+		return nil, err
+	}
+	return &davwriter{name: path.Base(fullpath), wc: wc}, nil
+}
+
+// RemoveAll implements webdav.FileSystem interface.
+func (dfs *DavFS) RemoveAll(ctx context.Context, name string) error {
+	var fullpath, err = dfs.fullpath(name)
+	if err != nil {
+		return err
+	}
+	return dfs.JointPool.RemoveAll(fullpath)
+}
+
+// Rename implements webdav.FileSystem interface.
+func (dfs *DavFS) Rename(ctx context.Context, oldName, newName string) error {
+	var oldpath, err = dfs.fullpath(oldName)
+	if err != nil {
+		return err
+	}
+	var newpath string
+	if newpath, err = dfs.fullpath(newName); err != nil {
+		return err
+	}
+	return dfs.JointPool.Rename(oldpath, newpath)
+}
+
+// Stat implements webdav.FileSystem interface.
+func (dfs *DavFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	var fullpath, err = dfs.fullpath(name)
+	if err != nil {
+		return nil, err
+	}
+	var fi fs.FileInfo
+	if fi, err = dfs.JointPool.StatContext(ctx, fullpath); err != nil {
+		return nil, err
+	}
+	return davinfo{fi}, nil
+}
+
+// davinfo provides entity tag and content type of file
+// without reading of its content.
+type davinfo struct {
+	fs.FileInfo
+}
+
+// ETag implements webdav.ETager interface.
+func (fi davinfo) ETag(ctx context.Context) (string, error) {
+	var etag = FileETag(fi.FileInfo)
+	if etag == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = `"` + etag + `"`
+	}
+	return etag, nil
+}
+
+// ContentType implements webdav.ContentTyper interface.
+func (fi davinfo) ContentType(ctx context.Context) (string, error) {
+	if ct := mime.TypeByExtension(path.Ext(fi.Name())); ct != "" {
+		return ct, nil
+	}
+	return "application/octet-stream", nil
+}
+
+// davfile is file opened for reading.
+type davfile struct {
+	fs.File
+}
+
+func (f *davfile) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := f.File.(io.Seeker); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, errors.ErrUnsupported
+}
+
+func (f *davfile) Readdir(count int) (list []fs.FileInfo, err error) {
+	var rdf, ok = f.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, fs.ErrInvalid
+	}
+	if count <= 0 {
+		count = -1 // some joints returns nothing for zero count
+	}
+	var des []fs.DirEntry
+	if des, err = rdf.ReadDir(count); err != nil && (err != io.EOF || len(des) == 0) {
+		return
+	}
+	for _, de := range des {
+		var fi, err = de.Info()
+		if err != nil {
+			continue
+		}
+		list = append(list, davinfo{fi})
+	}
+	return
+}
+
+func (f *davfile) Stat() (fs.FileInfo, error) {
+	var fi, err = f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return davinfo{fi}, nil
+}
+
+func (f *davfile) Write(p []byte) (int, error) {
+	return 0, fs.ErrPermission
+}
+
+// davwriter is file opened for writing, its content
+// is streamed to the joint.
+type davwriter struct {
+	name string
+	wc   io.WriteCloser
+	size int64
+}
+
+func (w *davwriter) Write(p []byte) (n int, err error) {
+	n, err = w.wc.Write(p)
+	w.size += int64(n)
+	return
+}
+
+func (w *davwriter) Close() error {
+	return w.wc.Close()
+}
+
+func (w *davwriter) Read(b []byte) (int, error) {
+	return 0, fs.ErrPermission
+}
+
+func (w *davwriter) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && (whence == io.SeekCurrent || whence == io.SeekEnd) {
+		return w.size, nil
+	}
+	return 0, errors.ErrUnsupported
+}
+
+func (w *davwriter) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, fs.ErrInvalid
+}
+
+func (w *davwriter) Stat() (fs.FileInfo, error) {
+	return putinfo{w.name, w.size, time.Now()}, nil
+}
+
+// putinfo describes file that is written now.
+type putinfo struct {
+	name  string
+	size  int64
+	mtime time.Time
+}
+
+func (fi putinfo) Name() string       { return fi.name }
+func (fi putinfo) Size() int64        { return fi.size }
+func (fi putinfo) Mode() fs.FileMode  { return 0644 }
+func (fi putinfo) ModTime() time.Time { return fi.mtime }
+func (fi putinfo) IsDir() bool        { return false }
+func (fi putinfo) Sys() interface{}   { return nil }
+
+// DavHandler serves WebDAV-requests with content of SubPool.
+type DavHandler struct {
+	webdav.Handler
+	// Allow PROPFIND requests with infinite depth. They are rejected
+	// by default, because containers makes files tree very large.
+	InfiniteDepth bool
+	// Reject all requests that can modify files.
+	ReadOnly bool
+}
+
+// NewDavHandler returns WebDAV-handler for given subsystem with
+// in-memory locks. Prefix is URL path prefix stripped from requests.
+func NewDavHandler(sp *SubPool, prefix string) *DavHandler {
+	return &DavHandler{
+		Handler: webdav.Handler{
+			Prefix:     prefix,
+			FileSystem: NewDavFS(sp),
+			LockSystem: webdav.NewMemLS(),
+		},
+	}
+}
+
+// ServeHTTP implements http.Handler interface.
+func (h *DavHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PROPFIND":
+		if depth := r.Header.Get("Depth"); !h.InfiniteDepth && (depth == "" || strings.EqualFold(depth, "infinity")) {
+			w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?>`+
+				`<D:error xmlns:D="DAV:"><D:propfind-finite-depth/></D:error>`)
+			return
+		}
+	case http.MethodPut, http.MethodDelete, "MKCOL", "COPY", "MOVE", "PROPPATCH", "LOCK", "UNLOCK":
+		if h.ReadOnly {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+	}
+	h.Handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,111 @@
+package joint_test
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+	"github.com/studio-b12/gowebdav"
+)
+
+func TestDavHandler(t *testing.T) {
+	var root = filepath.ToSlash(t.TempDir())
+	var b, err = os.ReadFile("testdata/external.iso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(root+"/external.iso", b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var sp = jnt.NewSubPool(nil, root)
+	defer sp.Close()
+	var srv = httptest.NewServer(jnt.NewDavHandler(sp, ""))
+	defer srv.Close()
+	var client = gowebdav.NewClient(srv.URL, "", "")
+
+	// read content of container
+	var list []fs.FileInfo
+	if list, err = client.ReadDir("/external.iso/data/docs"); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("number of files does not match: %v", list)
+	}
+	if b, err = client.Read("/external.iso/disk/internal.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != foxsize {
+		t.Fatal("size of file does not match")
+	}
+	var fi fs.FileInfo
+	if fi, err = client.Stat("/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+	if !fi.IsDir() {
+		t.Fatal("container should be represented as collection")
+	}
+
+	// modify files
+	if err = client.Mkdir("/dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Write("/dir/fox.txt", b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Rename("/dir/fox.txt", "/fox.txt", true); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = os.ReadFile(root + "/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != foxsize {
+		t.Fatal("size of written file does not match")
+	}
+	if err = client.RemoveAll("/dir"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(root + "/dir"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("directory should be removed")
+	}
+	if err = client.Write("/external.iso/fox2.txt", b, 0644); err == nil {
+		t.Fatal("container should not be writable")
+	}
+
+	// infinite depth
+	var req *http.Request
+	if req, err = http.NewRequest("PROPFIND", srv.URL+"/", nil); err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Depth", "infinity")
+	var resp *http.Response
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatal("request with infinite depth should be rejected")
+	}
+}
+
+func TestDavHandlerReadOnly(t *testing.T) {
+	var sp = jnt.NewSubPool(nil, filepath.ToSlash(t.TempDir()))
+	defer sp.Close()
+	var h = jnt.NewDavHandler(sp, "/dav/")
+	h.ReadOnly = true
+	var srv = httptest.NewServer(h)
+	defer srv.Close()
+	var client = gowebdav.NewClient(srv.URL+"/dav/", "", "")
+
+	if _, err := client.ReadDir("/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Mkdir("/dir", 0755); err == nil {
+		t.Fatal("directory should not be created by read-only handler")
+	}
+}
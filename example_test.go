@@ -51,9 +51,11 @@ func ExampleSplitKey() {
 		"testdata/external.iso",
 		"testdata/external.iso/fox.txt",
 		"testdata/external.iso/disk/internal.iso/fox.txt",
+		"Backup/Disc.Iso/fox.txt",
 		"ftp://music:x@192.168.1.1:21/Music",
 		"ftp://music:x@192.168.1.1:21/testdata/external.iso/disk/internal.iso/docs/doc1.txt",
 		"https://music:x@example.keenetic.link/webdav/Global%20Underground/Nubreed/",
+		"smb://music:x@192.168.1.1/share/Music/DJ.m3u",
 	}
 	jnt.SetDavRoot("https://music:x@example.keenetic.link", "/webdav/")
 	for _, s := range list {
@@ -65,9 +67,11 @@ func ExampleSplitKey() {
 	// key: 'testdata/external.iso', path: ''
 	// key: 'testdata/external.iso', path: 'fox.txt'
 	// key: 'testdata/external.iso/disk/internal.iso', path: 'fox.txt'
+	// key: 'Backup/Disc.Iso', path: 'fox.txt'
 	// key: 'ftp://music:x@192.168.1.1:21', path: 'Music'
 	// key: 'ftp://music:x@192.168.1.1:21/testdata/external.iso/disk/internal.iso', path: 'docs/doc1.txt'
 	// key: 'https://music:x@example.keenetic.link/webdav/', path: 'Global%20Underground/Nubreed/'
+	// key: 'smb://music:x@192.168.1.1/share', path: 'Music/DJ.m3u'
 }
 
 func ExampleFtpEscapeBrackets() {
@@ -0,0 +1,486 @@
+package joint
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrExtFormat  = errors.New("invalid ext2/3/4 file system")
+	ErrExtFeature = errors.New("unsupported ext4 feature")
+)
+
+// ext2/3/4 constants.
+const (
+	extMagic     = 0xef53
+	extRootIno   = 2
+	extExtMagic  = 0xf30a
+	extFlExtents = 0x80000
+	extFlInline  = 0x10000000
+
+	extIncompat64bit  = 0x80
+	extIncompatMetaBg = 0x10
+	extIncompatUnsup  = 0x1 | 0x10000 // compression, encryption
+)
+
+// ExtFS provides read-only access to ext2, ext3 or ext4
+// file system placed in raw image, and implements fs.FS interface.
+type ExtFS struct {
+	r     io.ReaderAt
+	bsize int64  // block size
+	isize int64  // inode size
+	ipg   uint32 // inodes per group
+	dsize int64  // group descriptor size
+	gdt   []byte // group descriptors table
+
+	cache map[string][]ExtFileInfo // directories content
+	mux   sync.Mutex
+}
+
+// IsExt checks up that given superblock contains ext2/3/4 magic number.
+func IsExt(sb []byte) bool {
+	return len(sb) >= 1024 && binary.LittleEndian.Uint16(sb[56:]) == extMagic
+}
+
+// OpenExt opens ext2/3/4 file system placed in given reader.
+func OpenExt(r io.ReaderAt) (e *ExtFS, err error) {
+	var sb [1024]byte
+	if _, err = r.ReadAt(sb[:], 1024); err != nil {
+		return
+	}
+	if !IsExt(sb[:]) {
+		return nil, ErrExtFormat
+	}
+	var le = binary.LittleEndian
+	var incompat = le.Uint32(sb[96:])
+	if incompat&(extIncompatUnsup|extIncompatMetaBg) != 0 {
+		return nil, ErrExtFeature
+	}
+	e = &ExtFS{
+		r:     r,
+		bsize: 1024 << le.Uint32(sb[24:]),
+		isize: 128,
+		ipg:   le.Uint32(sb[40:]),
+		dsize: 32,
+		cache: map[string][]ExtFileInfo{},
+	}
+	if le.Uint32(sb[76:]) >= 1 { // dynamic revision
+		e.isize = int64(le.Uint16(sb[88:]))
+	}
+	var blocks = uint64(le.Uint32(sb[4:]))
+	if incompat&extIncompat64bit != 0 {
+		e.dsize = int64(le.Uint16(sb[254:]))
+		blocks |= uint64(le.Uint32(sb[0x150:])) << 32
+	}
+	var firstblock = uint64(le.Uint32(sb[20:]))
+	var bpg = uint64(le.Uint32(sb[32:]))
+	if bpg == 0 || e.ipg == 0 || e.isize < 128 || e.dsize < 32 || blocks <= firstblock {
+		return nil, ErrExtFormat
+	}
+	var groups = (blocks - firstblock + bpg - 1) / bpg
+	e.gdt = make([]byte, int64(groups)*e.dsize)
+	if _, err = r.ReadAt(e.gdt, int64(firstblock+1)*e.bsize); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// extinode is inode content required to read files.
+type extinode struct {
+	mode  uint16
+	size  int64
+	mtime time.Time
+	flags uint32
+	block []byte // 60 bytes of i_block field
+}
+
+// inode reads inode with given number.
+func (e *ExtFS) inode(ino uint32) (in extinode, err error) {
+	var le = binary.LittleEndian
+	var group, index = int64((ino - 1) / e.ipg), int64((ino - 1) % e.ipg)
+	if ino == 0 || (group+1)*e.dsize > int64(len(e.gdt)) {
+		return in, ErrExtFormat
+	}
+	var desc = e.gdt[group*e.dsize:]
+	var table = int64(le.Uint32(desc[8:]))
+	if e.dsize >= 64 {
+		table |= int64(le.Uint32(desc[0x28:])) << 32
+	}
+	var b = make([]byte, 128)
+	if _, err = e.r.ReadAt(b, table*e.bsize+index*e.isize); err != nil {
+		return
+	}
+	in.mode = le.Uint16(b[0:])
+	in.size = int64(le.Uint32(b[4:]))
+	if in.mode&0xf000 == 0x8000 { // high 32 bits of size for regular files
+		in.size |= int64(le.Uint32(b[108:])) << 32
+	}
+	in.mtime = time.Unix(int64(le.Uint32(b[16:])), 0)
+	in.flags = le.Uint32(b[32:])
+	in.block = b[40:100]
+	return
+}
+
+// extent maps continuous range of logical blocks to physical blocks.
+// Physical block 0 means sparse range filled by zeros.
+type extent struct {
+	lblk, pblk, n int64
+}
+
+// extents returns sorted list of extents for given inode.
+func (e *ExtFS) extents(in extinode) (list []extent, err error) {
+	if in.flags&extFlExtents != 0 {
+		err = e.exttree(in.block, &list, 0)
+	} else {
+		var le = binary.LittleEndian
+		var lblk int64
+		for i := 0; i < 12; i++ {
+			list = appendblk(list, lblk, int64(le.Uint32(in.block[i*4:])))
+			lblk++
+		}
+		for depth := 1; depth <= 3 && err == nil; depth++ {
+			var blk = int64(le.Uint32(in.block[(11+depth)*4:]))
+			if blk == 0 {
+				break
+			}
+			err = e.indirect(blk, depth, &lblk, &list)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].lblk < list[j].lblk })
+	return
+}
+
+// appendblk appends physical block to list of extents.
+func appendblk(list []extent, lblk, pblk int64) []extent {
+	if pblk == 0 {
+		return list
+	}
+	if l := len(list); l > 0 {
+		var last = &list[l-1]
+		if last.lblk+last.n == lblk && last.pblk+last.n == pblk {
+			last.n++
+			return list
+		}
+	}
+	return append(list, extent{lblk, pblk, 1})
+}
+
+// indirect reads blocks map of ext2/3 indirect block.
+func (e *ExtFS) indirect(blk int64, depth int, lblk *int64, list *[]extent) (err error) {
+	var b = make([]byte, e.bsize)
+	if _, err = e.r.ReadAt(b, blk*e.bsize); err != nil {
+		return
+	}
+	var span = int64(1)
+	for i := 1; i < depth; i++ {
+		span *= e.bsize / 4
+	}
+	for i := int64(0); i < e.bsize/4; i++ {
+		var ptr = int64(binary.LittleEndian.Uint32(b[i*4:]))
+		if depth == 1 {
+			*list = appendblk(*list, *lblk, ptr)
+			*lblk++
+		} else if ptr == 0 {
+			*lblk += span
+		} else if err = e.indirect(ptr, depth-1, lblk, list); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// exttree reads ext4 extents tree node.
+func (e *ExtFS) exttree(node []byte, list *[]extent, level int) (err error) {
+	var le = binary.LittleEndian
+	if len(node) < 12 || le.Uint16(node[0:]) != extExtMagic || level > 5 {
+		return ErrExtFormat
+	}
+	var entries = int(le.Uint16(node[2:]))
+	var depth = le.Uint16(node[6:])
+	if 12+entries*12 > len(node) {
+		return ErrExtFormat
+	}
+	for i := 0; i < entries; i++ {
+		var ent = node[12+i*12:]
+		if depth == 0 {
+			var n = int64(le.Uint16(ent[4:]))
+			var pblk = int64(le.Uint16(ent[6:]))<<32 | int64(le.Uint32(ent[8:]))
+			if n > 32768 { // uninitialized extent reads as zeros
+				continue
+			}
+			*list = append(*list, extent{int64(le.Uint32(ent[0:])), pblk, n})
+		} else {
+			var leaf = int64(le.Uint16(ent[8:]))<<32 | int64(le.Uint32(ent[4:]))
+			var b = make([]byte, e.bsize)
+			if _, err = e.r.ReadAt(b, leaf*e.bsize); err != nil {
+				return
+			}
+			if err = e.exttree(b, list, level+1); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// extreader reads file content by extents.
+type extreader struct {
+	e    *ExtFS
+	list []extent
+}
+
+func (er extreader) ReadAt(b []byte, off int64) (n int, err error) {
+	var bsize = er.e.bsize
+	for n < len(b) {
+		var pos = off + int64(n)
+		var lblk = pos / bsize
+		var i = sort.Search(len(er.list), func(i int) bool {
+			return er.list[i].lblk+er.list[i].n > lblk
+		})
+		var chunk int64
+		if i < len(er.list) && er.list[i].lblk <= lblk {
+			var ext = er.list[i]
+			chunk = min(int64(len(b)-n), (ext.lblk+ext.n)*bsize-pos)
+			var n1 int
+			n1, err = er.e.r.ReadAt(b[n:n+int(chunk)], ext.pblk*bsize+pos-ext.lblk*bsize)
+			n += n1
+			if err != nil {
+				return
+			}
+			continue
+		}
+		// sparse range
+		chunk = int64(len(b) - n)
+		if i < len(er.list) {
+			chunk = min(chunk, er.list[i].lblk*bsize-pos)
+		}
+		clear(b[n : n+int(chunk)])
+		n += int(chunk)
+	}
+	return
+}
+
+// content returns reader for file with given inode.
+func (e *ExtFS) content(in extinode) (*io.SectionReader, error) {
+	if in.flags&extFlInline != 0 {
+		if in.size > int64(len(in.block)) {
+			return nil, ErrExtFeature
+		}
+		return io.NewSectionReader(strings.NewReader(string(in.block[:in.size])), 0, in.size), nil
+	}
+	var list, err = e.extents(in)
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(extreader{e, list}, 0, in.size), nil
+}
+
+// readdirlock returns content of directory with given path.
+func (e *ExtFS) readdirlock(fpath string) (list []ExtFileInfo, err error) {
+	var ok bool
+	if list, ok = e.cache[fpath]; ok {
+		return
+	}
+	var fi ExtFileInfo
+	if fi, err = e.statlock(fpath); err != nil {
+		return
+	}
+	if !fi.IsRealDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: fpath, Err: fs.ErrInvalid}
+	}
+	var sr *io.SectionReader
+	if sr, err = e.content(fi.inode); err != nil {
+		return
+	}
+	var raw = make([]byte, sr.Size())
+	if _, err = sr.ReadAt(raw, 0); err != nil && err != io.EOF {
+		return
+	}
+	err = nil
+
+	var le = binary.LittleEndian
+	list = []ExtFileInfo{}
+	for pos := 0; pos+8 <= len(raw); {
+		var ino = le.Uint32(raw[pos:])
+		var reclen = int(le.Uint16(raw[pos+4:]))
+		var namelen = int(raw[pos+6])
+		if reclen < 8 || pos+8+namelen > len(raw) {
+			break
+		}
+		var name = string(raw[pos+8 : pos+8+namelen])
+		pos += reclen
+		if ino == 0 || name == "." || name == ".." {
+			continue
+		}
+		var efi = ExtFileInfo{name: name}
+		if efi.inode, err = e.inode(ino); err != nil {
+			return
+		}
+		list = append(list, efi)
+	}
+	e.cache[fpath] = list
+	return
+}
+
+func (e *ExtFS) statlock(fpath string) (fi ExtFileInfo, err error) {
+	if fpath == "" {
+		fi.name = "."
+		fi.inode, err = e.inode(extRootIno)
+		return
+	}
+	var dir, name = "", fpath
+	if i := strings.LastIndexByte(fpath, '/'); i != -1 {
+		dir, name = fpath[:i], fpath[i+1:]
+	}
+	var list []ExtFileInfo
+	if list, err = e.readdirlock(dir); err != nil {
+		return
+	}
+	for _, fi = range list {
+		if fi.name == name {
+			return
+		}
+	}
+	return fi, &fs.PathError{Op: "stat", Path: fpath, Err: fs.ErrNotExist}
+}
+
+// Stat implements fs.StatFS interface.
+func (e *ExtFS) Stat(fpath string) (fs.FileInfo, error) {
+	if !fs.ValidPath(fpath) {
+		return nil, &fs.PathError{Op: "stat", Path: fpath, Err: fs.ErrInvalid}
+	}
+	if fpath == "." {
+		fpath = ""
+	}
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	var fi, err = e.statlock(fpath)
+	if err != nil {
+		return nil, err
+	}
+	return fi, nil
+}
+
+// ReadDir implements fs.ReadDirFS interface.
+func (e *ExtFS) ReadDir(fpath string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(fpath) {
+		return nil, &fs.PathError{Op: "readdir", Path: fpath, Err: fs.ErrInvalid}
+	}
+	if fpath == "." {
+		fpath = ""
+	}
+	e.mux.Lock()
+	var files, err = e.readdirlock(fpath)
+	e.mux.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var list = make([]fs.DirEntry, len(files))
+	for i, fi := range files {
+		list[i] = fi
+	}
+	return list, nil
+}
+
+// Open implements fs.FS interface.
+func (e *ExtFS) Open(fpath string) (fs.File, error) {
+	var fi, err = e.Stat(fpath)
+	if err != nil {
+		return nil, err
+	}
+	var efi = fi.(ExtFileInfo)
+	if efi.IsRealDir() {
+		var list []fs.DirEntry
+		if list, err = e.ReadDir(fpath); err != nil {
+			return nil, err
+		}
+		return &imgdir{fi: fi, list: list}, nil
+	}
+	var sr *io.SectionReader
+	if sr, err = e.content(efi.inode); err != nil {
+		return nil, err
+	}
+	return &imgfile{fi: fi, SectionReader: sr}, nil
+}
+
+// ExtFileInfo describes file at ext2/3/4 file system,
+// and provides fs.FileInfo and fs.DirEntry implementation.
+type ExtFileInfo struct {
+	name  string
+	inode extinode
+}
+
+// fs.FileInfo implementation.
+func (fi ExtFileInfo) Name() string {
+	return fi.name
+}
+
+// fs.FileInfo implementation.
+func (fi ExtFileInfo) Size() int64 {
+	if fi.IsRealDir() {
+		return 0
+	}
+	return fi.inode.size
+}
+
+// fs.FileInfo implementation.
+func (fi ExtFileInfo) Mode() fs.FileMode {
+	var mode = fs.FileMode(fi.inode.mode & 0777)
+	switch fi.inode.mode & 0xf000 {
+	case 0x4000:
+		mode |= fs.ModeDir
+	case 0xa000:
+		mode |= fs.ModeSymlink
+	case 0x2000:
+		mode |= fs.ModeDevice | fs.ModeCharDevice
+	case 0x6000:
+		mode |= fs.ModeDevice
+	case 0x1000:
+		mode |= fs.ModeNamedPipe
+	case 0xc000:
+		mode |= fs.ModeSocket
+	case 0x8000:
+		if IsTypeContainer(fi.name) {
+			mode |= fs.ModeDir
+		}
+	}
+	return mode
+}
+
+// fs.FileInfo implementation.
+func (fi ExtFileInfo) ModTime() time.Time {
+	return fi.inode.mtime
+}
+
+// fs.FileInfo implementation.
+func (fi ExtFileInfo) IsDir() bool {
+	return fi.IsRealDir() || (fi.inode.mode&0xf000 == 0x8000 && IsTypeContainer(fi.name))
+}
+
+func (fi ExtFileInfo) IsRealDir() bool {
+	return fi.inode.mode&0xf000 == 0x4000
+}
+
+func (fi ExtFileInfo) Type() fs.FileMode {
+	return fi.Mode().Type()
+}
+
+// Info provided for fs.DirEntry compatibility and returns object itself.
+func (fi ExtFileInfo) Info() (fs.FileInfo, error) {
+	return fi, nil
+}
+
+// fs.FileInfo implementation. Returns structure itself.
+func (fi ExtFileInfo) Sys() interface{} {
+	return fi
+}
+
+func (fi ExtFileInfo) String() string {
+	return fs.FormatDirEntry(fi)
+}
@@ -0,0 +1,452 @@
+package joint
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+)
+
+var (
+	ErrFatFormat = errors.New("invalid FAT file system")
+)
+
+// FAT directory entry attributes.
+const (
+	fatAttrVolume = 0x08
+	fatAttrDir    = 0x10
+	fatAttrLFN    = 0x0f
+)
+
+// FatFS provides read-only access to FAT12, FAT16 or FAT32
+// file system placed in raw image, and implements fs.FS interface.
+type FatFS struct {
+	r        io.ReaderAt
+	kind     int    // 12, 16 or 32
+	csize    int64  // cluster size in bytes
+	fat      []byte // content of first FAT
+	rootoff  int64  // root directory offset for FAT12/16
+	rootsize int64  // root directory size for FAT12/16
+	rootclus uint32 // root directory first cluster for FAT32
+	dataoff  int64  // offset of data region
+	nclus    uint32 // number of data clusters
+
+	cache map[string][]FatFileInfo // directories content
+	mux   sync.Mutex
+}
+
+// IsFat checks up that given boot sector contains valid FAT BIOS parameter block.
+func IsFat(boot []byte) bool {
+	if len(boot) < 512 || (boot[0] != 0xeb && boot[0] != 0xe9) {
+		return false
+	}
+	var bps = binary.LittleEndian.Uint16(boot[11:])
+	var spc = boot[13]
+	if bps < 512 || bps > 4096 || bps&(bps-1) != 0 || spc == 0 || spc&(spc-1) != 0 {
+		return false
+	}
+	if binary.LittleEndian.Uint16(boot[14:]) == 0 || boot[16] == 0 { // reserved sectors and FATs number
+		return false
+	}
+	return string(boot[54:57]) == "FAT" || string(boot[82:87]) == "FAT32"
+}
+
+// OpenFat opens FAT file system placed in given reader.
+func OpenFat(r io.ReaderAt) (f *FatFS, err error) {
+	var boot [512]byte
+	if _, err = r.ReadAt(boot[:], 0); err != nil {
+		return
+	}
+	if !IsFat(boot[:]) {
+		return nil, ErrFatFormat
+	}
+	var le = binary.LittleEndian
+	var bps = int64(le.Uint16(boot[11:]))
+	var spc = int64(boot[13])
+	var reserved = int64(le.Uint16(boot[14:]))
+	var nfats = int64(boot[16])
+	var rootents = int64(le.Uint16(boot[17:]))
+	var total = int64(le.Uint16(boot[19:]))
+	if total == 0 {
+		total = int64(le.Uint32(boot[32:]))
+	}
+	var fatsize = int64(le.Uint16(boot[22:]))
+	if fatsize == 0 {
+		fatsize = int64(le.Uint32(boot[36:]))
+	}
+	var rootsecs = (rootents*32 + bps - 1) / bps
+	var datasec = reserved + nfats*fatsize + rootsecs
+	if datasec >= total {
+		return nil, ErrFatFormat
+	}
+
+	f = &FatFS{
+		r:        r,
+		csize:    bps * spc,
+		rootoff:  (reserved + nfats*fatsize) * bps,
+		rootsize: rootsecs * bps,
+		dataoff:  datasec * bps,
+		nclus:    uint32((total - datasec) / spc),
+		cache:    map[string][]FatFileInfo{},
+	}
+	switch {
+	case f.nclus < 4085:
+		f.kind = 12
+	case f.nclus < 65525:
+		f.kind = 16
+	default:
+		f.kind = 32
+		f.rootclus = le.Uint32(boot[44:])
+	}
+	f.fat = make([]byte, fatsize*bps)
+	if _, err = r.ReadAt(f.fat, reserved*bps); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// next returns next cluster in chain.
+func (f *FatFS) next(c uint32) uint32 {
+	switch f.kind {
+	case 12:
+		var off = int(c + c/2)
+		if off+1 >= len(f.fat) {
+			return 0
+		}
+		var v = binary.LittleEndian.Uint16(f.fat[off:])
+		if c&1 != 0 {
+			return uint32(v >> 4)
+		}
+		return uint32(v & 0xfff)
+	case 16:
+		if int(c*2+1) >= len(f.fat) {
+			return 0
+		}
+		return uint32(binary.LittleEndian.Uint16(f.fat[c*2:]))
+	default:
+		if int(c*4+3) >= len(f.fat) {
+			return 0
+		}
+		return binary.LittleEndian.Uint32(f.fat[c*4:]) & 0x0fffffff
+	}
+}
+
+// chain returns list of clusters started from given cluster.
+func (f *FatFS) chain(c uint32) (list []uint32, err error) {
+	for c >= 2 && c < f.nclus+2 {
+		if uint32(len(list)) > f.nclus { // loop in chain
+			return nil, ErrFatFormat
+		}
+		list = append(list, c)
+		c = f.next(c)
+	}
+	return
+}
+
+// fatreader reads content of clusters chain.
+type fatreader struct {
+	f    *FatFS
+	clus []uint32
+}
+
+func (cr fatreader) ReadAt(b []byte, off int64) (n int, err error) {
+	var csize = cr.f.csize
+	for n < len(b) {
+		var ci, co = (off + int64(n)) / csize, (off + int64(n)) % csize
+		if ci >= int64(len(cr.clus)) {
+			return n, io.EOF
+		}
+		var chunk = min(int64(len(b)-n), csize-co)
+		var pos = cr.f.dataoff + int64(cr.clus[ci]-2)*csize + co
+		var n1 int
+		n1, err = cr.f.r.ReadAt(b[n:n+int(chunk)], pos)
+		n += n1
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// content returns reader for file started from given cluster.
+func (f *FatFS) content(clus uint32, size int64) (*io.SectionReader, error) {
+	var list, err = f.chain(clus)
+	if err != nil {
+		return nil, err
+	}
+	if full := int64(len(list)) * f.csize; size > full {
+		size = full
+	}
+	return io.NewSectionReader(fatreader{f, list}, 0, size), nil
+}
+
+// fatdir parses raw FAT directory content.
+func fatdir(b []byte) (list []FatFileInfo) {
+	var le = binary.LittleEndian
+	var lfn []uint16
+	for i := 0; i+32 <= len(b); i += 32 {
+		var e = b[i : i+32]
+		if e[0] == 0 { // end of directory
+			break
+		}
+		if e[0] == 0xe5 { // deleted entry
+			lfn = nil
+			continue
+		}
+		var attr = e[11]
+		if attr&fatAttrLFN == fatAttrLFN {
+			var seq = int(e[0] & 0x1f)
+			if seq == 0 {
+				lfn = nil
+				continue
+			}
+			if e[0]&0x40 != 0 {
+				lfn = make([]uint16, seq*13)
+			}
+			if seq*13 > len(lfn) {
+				lfn = nil
+				continue
+			}
+			var chunk = lfn[(seq-1)*13:]
+			for j := 0; j < 5; j++ {
+				chunk[j] = le.Uint16(e[1+j*2:])
+			}
+			for j := 0; j < 6; j++ {
+				chunk[5+j] = le.Uint16(e[14+j*2:])
+			}
+			for j := 0; j < 2; j++ {
+				chunk[11+j] = le.Uint16(e[28+j*2:])
+			}
+			continue
+		}
+		if attr&fatAttrVolume != 0 {
+			lfn = nil
+			continue
+		}
+
+		var fi FatFileInfo
+		if lfn != nil {
+			var l = 0
+			for l < len(lfn) && lfn[l] != 0 && lfn[l] != 0xffff {
+				l++
+			}
+			fi.name = string(utf16.Decode(lfn[:l]))
+			lfn = nil
+		} else {
+			var base = strings.TrimRight(string(e[0:8]), " ")
+			var ext = strings.TrimRight(string(e[8:11]), " ")
+			if base != "" && base[0] == 0x05 {
+				base = "\xe5" + base[1:]
+			}
+			if e[12]&0x08 != 0 {
+				base = strings.ToLower(base)
+			}
+			if e[12]&0x10 != 0 {
+				ext = strings.ToLower(ext)
+			}
+			fi.name = base
+			if ext != "" {
+				fi.name += "." + ext
+			}
+		}
+		if fi.name == "." || fi.name == ".." {
+			continue
+		}
+		fi.attr = attr
+		fi.clus = uint32(le.Uint16(e[26:])) | uint32(le.Uint16(e[20:]))<<16
+		fi.size = int64(le.Uint32(e[28:]))
+		var d, t = le.Uint16(e[24:]), le.Uint16(e[22:])
+		fi.mtime = time.Date(1980+int(d>>9), time.Month(d>>5&0xf), int(d&0x1f),
+			int(t>>11), int(t>>5&0x3f), int(t&0x1f)*2, 0, time.Local)
+		list = append(list, fi)
+	}
+	return
+}
+
+// readdir returns content of directory with given path.
+func (f *FatFS) readdir(fpath string) (list []FatFileInfo, err error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.readdirlock(fpath)
+}
+
+func (f *FatFS) readdirlock(fpath string) (list []FatFileInfo, err error) {
+	var ok bool
+	if list, ok = f.cache[fpath]; ok {
+		return
+	}
+	var raw []byte
+	if fpath == "" && f.kind != 32 { // fixed root directory region
+		raw = make([]byte, f.rootsize)
+		if _, err = f.r.ReadAt(raw, f.rootoff); err != nil {
+			return
+		}
+	} else {
+		var fi FatFileInfo
+		if fi, err = f.statlock(fpath); err != nil {
+			return
+		}
+		if !fi.IsRealDir() {
+			return nil, &fs.PathError{Op: "readdir", Path: fpath, Err: fs.ErrInvalid}
+		}
+		var sr *io.SectionReader
+		if sr, err = f.content(fi.clus, 1<<32); err != nil {
+			return
+		}
+		raw = make([]byte, sr.Size())
+		if _, err = sr.ReadAt(raw, 0); err != nil && err != io.EOF {
+			return
+		}
+	}
+	err = nil
+	list = fatdir(raw)
+	f.cache[fpath] = list
+	return
+}
+
+func (f *FatFS) statlock(fpath string) (fi FatFileInfo, err error) {
+	if fpath == "" {
+		return FatFileInfo{name: ".", attr: fatAttrDir, clus: f.rootclus}, nil
+	}
+	var dir, name = "", fpath
+	if i := strings.LastIndexByte(fpath, '/'); i != -1 {
+		dir, name = fpath[:i], fpath[i+1:]
+	}
+	var list []FatFileInfo
+	if list, err = f.readdirlock(dir); err != nil {
+		return
+	}
+	for _, fi = range list {
+		if strings.EqualFold(fi.name, name) { // FAT is case-insensitive
+			return
+		}
+	}
+	return fi, &fs.PathError{Op: "stat", Path: fpath, Err: fs.ErrNotExist}
+}
+
+// Stat implements fs.StatFS interface.
+func (f *FatFS) Stat(fpath string) (fs.FileInfo, error) {
+	if !fs.ValidPath(fpath) {
+		return nil, &fs.PathError{Op: "stat", Path: fpath, Err: fs.ErrInvalid}
+	}
+	if fpath == "." {
+		fpath = ""
+	}
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	var fi, err = f.statlock(fpath)
+	if err != nil {
+		return nil, err
+	}
+	return fi, nil
+}
+
+// ReadDir implements fs.ReadDirFS interface.
+func (f *FatFS) ReadDir(fpath string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(fpath) {
+		return nil, &fs.PathError{Op: "readdir", Path: fpath, Err: fs.ErrInvalid}
+	}
+	if fpath == "." {
+		fpath = ""
+	}
+	var files, err = f.readdir(fpath)
+	if err != nil {
+		return nil, err
+	}
+	var list = make([]fs.DirEntry, len(files))
+	for i, fi := range files {
+		list[i] = fi
+	}
+	return list, nil
+}
+
+// Open implements fs.FS interface.
+func (f *FatFS) Open(fpath string) (fs.File, error) {
+	var fi, err = f.Stat(fpath)
+	if err != nil {
+		return nil, err
+	}
+	var ffi = fi.(FatFileInfo)
+	if ffi.IsRealDir() {
+		var list []fs.DirEntry
+		if list, err = f.ReadDir(fpath); err != nil {
+			return nil, err
+		}
+		return &imgdir{fi: fi, list: list}, nil
+	}
+	var sr *io.SectionReader
+	if sr, err = f.content(ffi.clus, ffi.size); err != nil {
+		return nil, err
+	}
+	return &imgfile{fi: fi, SectionReader: sr}, nil
+}
+
+// FatFileInfo describes file at FAT file system,
+// and provides fs.FileInfo and fs.DirEntry implementation.
+type FatFileInfo struct {
+	name  string
+	size  int64
+	attr  byte
+	clus  uint32 // first cluster
+	mtime time.Time
+}
+
+// fs.FileInfo implementation.
+func (fi FatFileInfo) Name() string {
+	return fi.name
+}
+
+// fs.FileInfo implementation.
+func (fi FatFileInfo) Size() int64 {
+	if fi.IsRealDir() {
+		return 0
+	}
+	return fi.size
+}
+
+// fs.FileInfo implementation.
+func (fi FatFileInfo) Mode() fs.FileMode {
+	if fi.IsRealDir() {
+		return fs.ModeDir | 0555
+	}
+	if IsTypeContainer(fi.name) {
+		return fs.ModeDir | 0444
+	}
+	return 0444
+}
+
+// fs.FileInfo implementation.
+func (fi FatFileInfo) ModTime() time.Time {
+	return fi.mtime
+}
+
+// fs.FileInfo implementation.
+func (fi FatFileInfo) IsDir() bool {
+	return fi.IsRealDir() || IsTypeContainer(fi.name)
+}
+
+func (fi FatFileInfo) IsRealDir() bool {
+	return fi.attr&fatAttrDir != 0
+}
+
+func (fi FatFileInfo) Type() fs.FileMode {
+	return fi.Mode().Type()
+}
+
+// Info provided for fs.DirEntry compatibility and returns object itself.
+func (fi FatFileInfo) Info() (fs.FileInfo, error) {
+	return fi, nil
+}
+
+// fs.FileInfo implementation. Returns structure itself.
+func (fi FatFileInfo) Sys() interface{} {
+	return fi
+}
+
+func (fi FatFileInfo) String() string {
+	return fs.FormatDirEntry(fi)
+}
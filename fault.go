@@ -0,0 +1,223 @@
+package joint
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrFault = errors.New("injected fault")
+)
+
+// Faults describes failures injected by FaultJoint. It's shared by all
+// joints that it wraps, so calls are counted over all of them, and
+// failures are deterministic for given sequence of calls.
+type Faults struct {
+	// Calls of joint methods with given names fail on given
+	// sequence numbers counted from 1, i.e. {"Open": {2, 3}} fails
+	// the second and the third Open calls. "Make" is failed before
+	// the joint is made.
+	FailOn map[string][]int
+	// Error returned by failed calls, ErrFault is used if it's nil.
+	Err error
+	// Maximum random delay before each call.
+	Latency time.Duration
+	// Delay before the joint is made.
+	MakeDelay time.Duration
+	// Maximum number of bytes returned by each Read and ReadAt call,
+	// if it's positive. ReadAt returns io.ErrUnexpectedEOF on short read.
+	ShortRead int
+	// Read and ReadAt return connection reset error after given
+	// number of bytes was read from opened file, if it's positive.
+	ResetAfter int64
+	// Source of random latency, global source is used if it's nil.
+	Rand *rand.Rand
+
+	calls map[string]int
+	mux   sync.Mutex
+}
+
+// WithFaults injects given failures into all joints made by JointPool.
+func WithFaults(f *Faults) PoolOption {
+	return func(jp *JointPool) {
+		jp.faults = f
+	}
+}
+
+// Calls returns number of calls of joint method with given name.
+func (f *Faults) Calls(op string) int {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.calls[op]
+}
+
+// call counts call of method with given name, waits for random latency,
+// and returns injected error if this call should fail.
+func (f *Faults) call(op string) error {
+	f.mux.Lock()
+	if f.calls == nil {
+		f.calls = map[string]int{}
+	}
+	f.calls[op]++
+	var n = f.calls[op]
+	var delay time.Duration
+	if f.Latency > 0 {
+		if f.Rand != nil {
+			delay = time.Duration(f.Rand.Int63n(int64(f.Latency) + 1))
+		} else {
+			delay = time.Duration(rand.Int63n(int64(f.Latency) + 1))
+		}
+	}
+	f.mux.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	for _, i := range f.FailOn[op] {
+		if i == n {
+			if f.Err != nil {
+				return f.Err
+			}
+			return ErrFault
+		}
+	}
+	return nil
+}
+
+// make waits for MakeDelay and counts Make call.
+func (f *Faults) make() error {
+	if f.MakeDelay > 0 {
+		time.Sleep(f.MakeDelay)
+	}
+	return f.call("Make")
+}
+
+// makejoint makes joint for given key and wraps it by FaultJoint.
+func (f *Faults) makejoint(ctx context.Context, tr Tracer, tc *tracectx, ml *makelog, sc *sniffcache, key string) (j Joint, err error) {
+	if err = f.make(); err != nil {
+		return
+	}
+	if j, err = makejoint(ctx, tr, tc, ml, sc, key); err != nil {
+		return
+	}
+	return &FaultJoint{Joint: j, Faults: f}, nil
+}
+
+// FaultJoint wraps any joint and injects failures described by Faults,
+// to test behavior of application on unreliable resources. Joints made
+// by JointPool with WithFaults option are wrapped by it.
+// Optional interfaces of wrapped joint are not provided, so it's
+// read-only joint without symbolic links.
+type FaultJoint struct {
+	Joint
+	Faults *Faults
+	read   int64 // number of bytes read from opened file
+}
+
+func (j *FaultJoint) Make(base Joint, addr string) error {
+	if err := j.Faults.make(); err != nil {
+		return err
+	}
+	return j.Joint.Make(base, addr)
+}
+
+func (j *FaultJoint) Cleanup() error {
+	var err1 = j.Faults.call("Cleanup")
+	return errors.Join(j.Joint.Cleanup(), err1)
+}
+
+func (j *FaultJoint) Open(fpath string) (file fs.File, err error) {
+	if err = j.Faults.call("Open"); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: fpath, Err: err}
+	}
+	if _, err = j.Joint.Open(fpath); err != nil {
+		return
+	}
+	j.read = 0
+	return j, nil
+}
+
+func (j *FaultJoint) Close() error {
+	var err1 = j.Faults.call("Close")
+	return errors.Join(j.Joint.Close(), err1)
+}
+
+func (j *FaultJoint) Size() (int64, error) {
+	if err := j.Faults.call("Size"); err != nil {
+		return 0, err
+	}
+	return j.Joint.Size()
+}
+
+func (j *FaultJoint) ReadDir(n int) ([]fs.DirEntry, error) {
+	if err := j.Faults.call("ReadDir"); err != nil {
+		return nil, err
+	}
+	return j.Joint.ReadDir(n)
+}
+
+func (j *FaultJoint) Stat() (fs.FileInfo, error) {
+	if err := j.Faults.call("Stat"); err != nil {
+		return nil, err
+	}
+	return j.Joint.Stat()
+}
+
+func (j *FaultJoint) Seek(offset int64, whence int) (int64, error) {
+	if err := j.Faults.call("Seek"); err != nil {
+		return 0, err
+	}
+	return j.Joint.Seek(offset, whence)
+}
+
+// limit returns buffer shortened in accordance with ShortRead
+// and ResetAfter, or connection reset error.
+func (j *FaultJoint) limit(op string, b []byte) ([]byte, error) {
+	if f := j.Faults; f.ResetAfter > 0 {
+		if j.read >= f.ResetAfter {
+			return nil, &net.OpError{Op: op, Net: "tcp", Err: syscall.ECONNRESET}
+		}
+		if rest := f.ResetAfter - j.read; int64(len(b)) > rest {
+			b = b[:rest]
+		}
+	}
+	if n := j.Faults.ShortRead; n > 0 && len(b) > n {
+		b = b[:n]
+	}
+	return b, nil
+}
+
+func (j *FaultJoint) Read(b []byte) (n int, err error) {
+	if err = j.Faults.call("Read"); err != nil {
+		return
+	}
+	if b, err = j.limit("read", b); err != nil {
+		return
+	}
+	n, err = j.Joint.Read(b)
+	j.read += int64(n)
+	return
+}
+
+func (j *FaultJoint) ReadAt(b []byte, off int64) (n int, err error) {
+	if err = j.Faults.call("ReadAt"); err != nil {
+		return
+	}
+	var p []byte
+	if p, err = j.limit("readat", b); err != nil {
+		return
+	}
+	n, err = j.Joint.ReadAt(p, off)
+	j.read += int64(n)
+	if err == nil && n < len(b) {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
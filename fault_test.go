@@ -0,0 +1,100 @@
+package joint_test
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"syscall"
+	"testing"
+	"time"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+	"github.com/schwarzlichtbezirk/joint/jointtest"
+)
+
+// Joint without injected failures passes the contract.
+func TestFaultJointContract(t *testing.T) {
+	var f = &jnt.Faults{
+		Latency: time.Millisecond,
+		Rand:    rand.New(rand.NewSource(1)),
+	}
+	jointtest.TestJoint(t, func() (jnt.Joint, error) {
+		var j = &jnt.FaultJoint{Joint: &jnt.SysJoint{}, Faults: f}
+		return j, j.Make(nil, "")
+	}, "testdata")
+}
+
+// Joint that fails on opening is dropped from the cache.
+func TestFaultPoolOpen(t *testing.T) {
+	var f = &jnt.Faults{
+		FailOn:    map[string][]int{"Open": {1}},
+		MakeDelay: 20 * time.Millisecond,
+	}
+	var jp = jnt.NewJointPool(jnt.WithFaults(f))
+	defer jp.Close()
+	var jc = jp.GetCache("testdata/external.iso")
+
+	var t0 = time.Now()
+	var _, err = jp.Open("testdata/external.iso/fox.txt")
+	if !errors.Is(err, jnt.ErrFault) {
+		t.Fatalf("expected injected fault, got %v", err)
+	}
+	if time.Since(t0) < f.MakeDelay {
+		t.Fatal("making of joint is not delayed")
+	}
+	if jc.Count() != 0 {
+		t.Fatal("failed joint should be dropped")
+	}
+
+	var file io.ReadCloser
+	if file, err = jp.Open("testdata/external.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	var b []byte
+	b, err = io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != foxsize {
+		t.Fatal("size of file does not match")
+	}
+	if jc.Count() != 1 {
+		t.Fatal("joint should be returned to the cache")
+	}
+	if f.Calls("Make") != 2 || f.Calls("Open") != 2 {
+		t.Fatalf("unexpected number of calls: %d Make, %d Open", f.Calls("Make"), f.Calls("Open"))
+	}
+}
+
+// Connection is reset in the middle of file reading.
+func TestFaultRead(t *testing.T) {
+	var f = &jnt.Faults{
+		ShortRead:  4,
+		ResetAfter: 10,
+	}
+	var jp = jnt.NewJointPool(jnt.WithFaults(f))
+	defer jp.Close()
+
+	var file, err = jp.Open("testdata/external.iso/fox.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var b = make([]byte, foxsize)
+	var n int
+	if n, _ = file.(io.Reader).Read(b); n != f.ShortRead {
+		t.Fatalf("expected short read of %d bytes, got %d", f.ShortRead, n)
+	}
+	var rest []byte
+	rest, err = io.ReadAll(file)
+	if !errors.Is(err, syscall.ECONNRESET) {
+		t.Fatalf("expected connection reset, got %v", err)
+	}
+	if n+len(rest) != int(f.ResetAfter) {
+		t.Fatalf("expected %d bytes before reset, got %d", f.ResetAfter, n+len(rest))
+	}
+	if _, err = file.(io.ReaderAt).ReadAt(b, 0); !errors.Is(err, syscall.ECONNRESET) {
+		t.Fatalf("expected connection reset, got %v", err)
+	}
+}
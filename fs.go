@@ -0,0 +1,279 @@
+package joint
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+var (
+	ErrFsWhence = errors.New("invalid whence at FS seeker")
+	ErrFsNegPos = errors.New("negative position at FS seeker")
+)
+
+// fsmap is global map of mounted file systems by its addresses.
+var fsmap = map[string]fs.FS{}
+var fsmux sync.RWMutex
+
+// MountFS associates given file system with address. Address should
+// be in URL-like form with scheme, i.e. embed://assets, and after that
+// any path started with this address can be opened by JointPool,
+// including nested ISO-images.
+func MountFS(addr string, fsys fs.FS) {
+	fsmux.Lock()
+	fsmap[addr] = fsys
+	fsmux.Unlock()
+}
+
+// UnmountFS removes file system associated with given address.
+func UnmountFS(addr string) {
+	fsmux.Lock()
+	delete(fsmap, addr)
+	fsmux.Unlock()
+}
+
+// GetFS returns file system mounted to given address.
+func GetFS(addr string) (fsys fs.FS, ok bool) {
+	fsmux.RLock()
+	fsys, ok = fsmap[addr]
+	fsmux.RUnlock()
+	return
+}
+
+// FSJoint adapts any fs.FS to Joint interface, i.e. embed.FS,
+// fstest.MapFS, os.DirFS, zip.Reader. It uses ReadAt and Seek of opened
+// files if they support it, otherwise file content is buffered on first
+// random access call.
+// Key is address at which file system was mounted by MountFS,
+// or FS field can be set before Make call.
+type FSJoint struct {
+	FS fs.FS
+
+	path  string // path inside of file system
+	files []fs.DirEntry
+	fs.File
+	buf *bytes.Reader // buffered file content if file has no random access
+	pos int64
+	rdn int
+}
+
+func (j *FSJoint) Make(base Joint, addr string) (err error) {
+	if j.FS == nil {
+		var ok bool
+		if j.FS, ok = GetFS(addr); !ok {
+			return fs.ErrNotExist
+		}
+	}
+	return
+}
+
+func (j *FSJoint) Cleanup() error {
+	var err1 error
+	if j.Busy() {
+		err1 = j.Close()
+	}
+	return err1
+}
+
+func (j *FSJoint) Busy() bool {
+	return j.File != nil
+}
+
+func (j *FSJoint) Open(fpath string) (file fs.File, err error) {
+	if j.Busy() {
+		return nil, fs.ErrExist
+	}
+	if fpath == "" {
+		fpath = "."
+	}
+	if j.File, err = j.FS.Open(fpath); err != nil {
+		return
+	}
+	j.path = fpath
+	j.files = nil // delete previous readdir result
+	j.rdn = 0     // start new sequence
+	return j, nil
+}
+
+func (j *FSJoint) Close() (err error) {
+	j.path = ""
+	if j.File != nil {
+		err = j.File.Close()
+		j.File = nil
+	}
+	j.buf = nil
+	j.pos = 0
+	return
+}
+
+// buffer reads whole file content into memory to provide random access.
+func (j *FSJoint) buffer() (err error) {
+	if j.buf != nil {
+		return
+	}
+	var f fs.File
+	if f, err = j.FS.Open(j.path); err != nil {
+		return
+	}
+	defer f.Close()
+	var b []byte
+	if b, err = io.ReadAll(f); err != nil {
+		return
+	}
+	j.buf = bytes.NewReader(b)
+	_, err = j.buf.Seek(j.pos, io.SeekStart)
+	return
+}
+
+func (j *FSJoint) Size() (int64, error) {
+	var fi, err = j.File.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+func (j *FSJoint) ReadDir(n int) (list []fs.DirEntry, err error) {
+	if j.files == nil {
+		if j.files, err = fs.ReadDir(j.FS, j.path); err != nil {
+			return
+		}
+	}
+
+	if n < 0 {
+		n = len(j.files) - j.rdn
+	} else if n > len(j.files)-j.rdn {
+		n = len(j.files) - j.rdn
+		err = io.EOF
+	}
+	if n <= 0 { // on case all files readed or some deleted
+		return
+	}
+	list = make([]fs.DirEntry, n)
+	var errs = []error{err}
+	for i := 0; i < n; i++ {
+		var de = j.files[j.rdn+i]
+		list[i] = de
+		var fi, err = de.Info()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		list[i] = ToDirEntry(fi)
+	}
+	j.rdn += n
+	if len(errs) > 1 {
+		err = errors.Join(errs...)
+	}
+	return
+}
+
+func (j *FSJoint) Read(b []byte) (n int, err error) {
+	if len(b) == 0 { // some archive readers loops on empty buffer
+		return
+	}
+	if j.buf != nil {
+		n, err = j.buf.Read(b)
+	} else {
+		n, err = j.File.Read(b)
+	}
+	j.pos += int64(n)
+	return
+}
+
+func (j *FSJoint) Seek(offset int64, whence int) (abs int64, err error) {
+	if s, ok := j.File.(io.Seeker); ok && j.buf == nil {
+		if abs, err = s.Seek(offset, whence); err != nil {
+			return
+		}
+		j.pos = abs
+		return
+	}
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = j.pos + offset
+	case io.SeekEnd:
+		var size int64
+		if size, err = j.Size(); err != nil {
+			return
+		}
+		abs = size + offset
+	default:
+		err = ErrFsWhence
+		return
+	}
+	if abs < 0 {
+		err = ErrFsNegPos
+		return
+	}
+	if abs != j.pos {
+		if err = j.buffer(); err != nil {
+			return
+		}
+		if _, err = j.buf.Seek(abs, io.SeekStart); err != nil {
+			return
+		}
+	}
+	j.pos = abs
+	return
+}
+
+func (j *FSJoint) ReadAt(b []byte, off int64) (n int, err error) {
+	if off < 0 {
+		err = ErrFsNegPos
+		return
+	}
+	if ra, ok := j.File.(io.ReaderAt); ok {
+		return ra.ReadAt(b, off)
+	}
+	if err = j.buffer(); err != nil {
+		return
+	}
+	return j.buf.ReadAt(b, off)
+}
+
+func (j *FSJoint) Stat() (fs.FileInfo, error) {
+	var fi, err = j.File.Stat()
+	return ToFileInfo(fi), err
+}
+
+func (j *FSJoint) Info(fpath string) (fs.FileInfo, error) {
+	if fpath == "" {
+		fpath = "."
+	}
+	var fi, err = fs.Stat(j.FS, fpath)
+	return ToFileInfo(fi), err
+}
+
+// readlinkfs is file system with symbolic links,
+// it's the same as fs.ReadLinkFS at Go 1.25.
+type readlinkfs interface {
+	fs.FS
+	ReadLink(name string) (string, error)
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// Lstat returns file info without following of symbolic link
+// if file system supports links, or the same as Info otherwise.
+func (j *FSJoint) Lstat(fpath string) (fs.FileInfo, error) {
+	if fpath == "" {
+		fpath = "."
+	}
+	if lfs, ok := j.FS.(readlinkfs); ok {
+		var fi, err = lfs.Lstat(fpath)
+		return ToFileInfo(fi), err
+	}
+	return j.Info(fpath)
+}
+
+// ReadLink returns target of symbolic link if file system supports links.
+func (j *FSJoint) ReadLink(fpath string) (string, error) {
+	if lfs, ok := j.FS.(readlinkfs); ok {
+		return lfs.ReadLink(fpath)
+	}
+	return "", &fs.PathError{Op: "readlink", Path: fpath, Err: fs.ErrInvalid}
+}
@@ -0,0 +1,122 @@
+package joint_test
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// Address at which "testdata" folder is mounted for tests.
+const fsaddr = "dirfs://testdata"
+
+// streamfs provides files that supports only sequential reading.
+type streamfs struct {
+	fs.FS
+}
+
+type streamfile struct {
+	f fs.File
+}
+
+func (f streamfile) Stat() (fs.FileInfo, error) { return f.f.Stat() }
+func (f streamfile) Read(b []byte) (int, error) { return f.f.Read(b) }
+func (f streamfile) Close() error               { return f.f.Close() }
+
+func (sfs streamfs) Open(fpath string) (fs.File, error) {
+	var f, err = sfs.FS.Open(fpath)
+	if err != nil {
+		return nil, err
+	}
+	return streamfile{f}, nil
+}
+
+func TestFSJoint(t *testing.T) {
+	var err error
+
+	var j = &jnt.FSJoint{FS: os.DirFS(".")}
+	if err = j.Make(nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer j.Cleanup()
+
+	var fi fs.FileInfo
+	if fi, err = j.Info("testdata/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+
+	if fi.Size() != isosize {
+		t.Fatal("ISO-file size does not match")
+	}
+
+	if err = checkReadDir(j); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Check ISO-disk reading placed on file system without random access.
+func TestFSStreamReadChunk(t *testing.T) {
+	var err error
+
+	var j1 jnt.Joint = &jnt.FSJoint{FS: streamfs{os.DirFS(".")}}
+	if err = j1.Make(nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer j1.Cleanup()
+
+	var j2 jnt.Joint = &jnt.IsoJoint{}
+	if err = readChunk(j2, j1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Check file reading in internal ISO-disk placed at mounted file system.
+func TestFSMountReadFile(t *testing.T) {
+	var err error
+
+	jnt.MountFS(fsaddr, os.DirFS("testdata"))
+	defer jnt.UnmountFS(fsaddr)
+
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var f fs.File
+	if f, err = jp.Open(fsaddr + "/external.iso/disk/internal.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var b []byte
+	if b, err = io.ReadAll(f); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "The quick brown fox jumps over the lazy dog." {
+		t.Fatal("read string does not match to pattern")
+	}
+}
+
+func TestFSMountPool(t *testing.T) {
+	var err error
+
+	jnt.MountFS("mapfs://assets", fstest.MapFS{
+		"fox.txt":      {Data: []byte("The quick brown fox jumps over the lazy dog.")},
+		"css/main.css": {Data: []byte("body {}")},
+	})
+	defer jnt.UnmountFS("mapfs://assets")
+
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var sp fs.FS
+	if sp, err = jp.Sub("mapfs://assets"); err != nil {
+		t.Fatal(err)
+	}
+
+	// test FS at the end
+	if err = fstest.TestFS(sp, "fox.txt", "css/main.css"); err != nil {
+		t.Fatal(err)
+	}
+}
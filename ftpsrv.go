@@ -0,0 +1,407 @@
+package joint
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"time"
+
+	ftpserver "github.com/fclairamb/ftpserverlib"
+	gklog "github.com/fclairamb/go-log/slog"
+	"github.com/spf13/afero"
+)
+
+var (
+	errFtpNotFile = errors.New("not a plain file")
+	errFtpNotDir  = errors.New("not a directory")
+	errFtpAction  = errors.New("requested action not taken")
+)
+
+// FtpServer is FTP-server that shares content of the pool. It's driver
+// of ftpserverlib server, that serves FTP protocol. Each user has own
+// root directory at the pool, and read-only or read-write access to it.
+// Containers are represented as directories, so content of ISO-images
+// or archives can be browsed by any FTP-client, and files can be
+// uploaded if they are placed at writable joints. Only passive mode
+// of data connections is supported, and data connections are accepted
+// only from address of client.
+type FtpServer struct {
+	// Accounts of server users.
+	Users ServerUsers
+	// IPv4-address sent to clients in reply to PASV command. Local
+	// address of control connection is used if it's empty.
+	PassiveIP string
+	// Maximum time of waiting for command from client, connection
+	// is closed after it. It's 15 minutes if it's zero.
+	IdleTimeout time.Duration
+
+	jp *JointPool
+	sc srvconns
+}
+
+// NewFtpServer returns FTP-server with given pool and users accounts.
+func NewFtpServer(jp *JointPool, users ServerUsers) *FtpServer {
+	if jp == nil {
+		jp = NewJointPool()
+	}
+	return &FtpServer{
+		Users: users,
+		jp:    jp,
+	}
+}
+
+// Serve accepts incoming connections on the listener, and serves
+// each connection in new goroutine. Always returns non-nil error,
+// ErrSrvClosed after Close call.
+func (s *FtpServer) Serve(l net.Listener) (err error) {
+	if !s.sc.add(l) {
+		return ErrSrvClosed
+	}
+	defer s.sc.remove(l)
+	var srv = ftpserver.NewFtpServer(&ftpdriver{srv: s, l: l})
+	srv.Logger = gklog.NewWrap(s.log())
+	if err = srv.Listen(); err == nil {
+		err = srv.Serve()
+	}
+	if s.sc.closed() {
+		return ErrSrvClosed
+	}
+	if err == nil { // listener was closed outside
+		err = net.ErrClosed
+	}
+	return
+}
+
+// ListenAndServe listens on the TCP network address and then
+// calls Serve to handle incoming connections.
+func (s *FtpServer) ListenAndServe(addr string) error {
+	var l, err = net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Close closes all listeners and active connections.
+func (s *FtpServer) Close() error {
+	return s.sc.close()
+}
+
+// log returns logger of the pool with attributes of the server.
+func (s *FtpServer) log() *slog.Logger {
+	return s.jp.logger().With("protocol", "ftp")
+}
+
+// ftpdriver implements ftpserverlib.MainDriver interface
+// for server listening on given listener.
+type ftpdriver struct {
+	srv *FtpServer
+	l   net.Listener
+}
+
+func (d *ftpdriver) GetSettings() (*ftpserver.Settings, error) {
+	return &ftpserver.Settings{
+		Listener:          d.l,
+		PublicHost:        d.srv.PassiveIP,
+		IdleTimeout:       int(d.srv.IdleTimeout / time.Second),
+		ConnectionTimeout: int(Cfg.DialTimeout / time.Second),
+		DisableActiveMode: true,
+		Banner:            "Service ready",
+	}, nil
+}
+
+func (d *ftpdriver) ClientConnected(cc ftpserver.ClientContext) (string, error) {
+	if !d.srv.sc.add(cc) {
+		return "Service closing", ErrSrvClosed
+	}
+	return "Service ready", nil
+}
+
+func (d *ftpdriver) ClientDisconnected(cc ftpserver.ClientContext) {
+	d.srv.sc.remove(cc)
+}
+
+func (d *ftpdriver) AuthUser(cc ftpserver.ClientContext, user, pass string) (ftpserver.ClientDriver, error) {
+	var log = d.srv.log().With("remote", cc.RemoteAddr().String(), "user", user)
+	var sp, acc, err = d.srv.Users.Login(d.srv.jp, user, pass)
+	if err != nil {
+		log.Warn("login failed", "err", err)
+		return nil, err
+	}
+	log.Info("user logged in")
+	return &ftpfs{sp: sp, acc: acc, log: log}, nil
+}
+
+func (d *ftpdriver) GetTLSConfig() (*tls.Config, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// ftpfs implements ftpserverlib.ClientDriver interface over SubPool
+// with root directory of logged in user. Files are transferred and
+// directories are listed by driver extensions, so files of afero
+// are not used.
+type ftpfs struct {
+	sp  *SubPool
+	acc ServerUser   // account of logged in user
+	log *slog.Logger // logger with attributes of the session
+}
+
+// err converts error to error sent to FTP-client. Error text is
+// not sent to client to prevent disclosing of paths at the pool.
+func (c *ftpfs) err(err error) error {
+	switch {
+	case err == nil, err == io.EOF:
+		return err
+	case errors.Is(err, errFtpNotFile), errors.Is(err, errFtpNotDir):
+		return err
+	case errors.Is(err, fs.ErrNotExist):
+		return fs.ErrNotExist
+	case errors.Is(err, fs.ErrExist):
+		return fs.ErrExist
+	case errors.Is(err, fs.ErrPermission), errors.Is(err, ErrReadOnly):
+		return fs.ErrPermission
+	case errors.Is(err, fs.ErrInvalid):
+		return ftpserver.ErrFileNameNotAllowed
+	case errors.Is(err, errors.ErrUnsupported):
+		return errors.ErrUnsupported
+	}
+	c.log.Warn("request failed", "err", err)
+	return errFtpAction
+}
+
+// write calls given function with full path of file
+// if user can modify files.
+func (c *ftpfs) write(name string, f func(fullpath string) error) error {
+	if !c.acc.Writable {
+		return fs.ErrPermission
+	}
+	var fullpath, err = c.sp.fullpath(name)
+	if err != nil {
+		return c.err(err)
+	}
+	return c.err(f(fullpath))
+}
+
+func (c *ftpfs) Name() string {
+	return "joint"
+}
+
+func (c *ftpfs) Create(name string) (afero.File, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (c *ftpfs) Open(name string) (afero.File, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (c *ftpfs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (c *ftpfs) Chmod(name string, mode os.FileMode) error {
+	return errors.ErrUnsupported
+}
+
+func (c *ftpfs) Chown(name string, uid, gid int) error {
+	return errors.ErrUnsupported
+}
+
+func (c *ftpfs) Chtimes(name string, atime, mtime time.Time) error {
+	return c.write(name, func(fullpath string) error {
+		return c.sp.JointPool.Chtimes(fullpath, mtime)
+	})
+}
+
+func (c *ftpfs) Stat(name string) (fi os.FileInfo, err error) {
+	var fullpath string
+	if fullpath, err = c.sp.fullpath(name); err != nil {
+		return nil, c.err(err)
+	}
+	if fi, err = c.sp.JointPool.Stat(fullpath); err != nil {
+		return nil, c.err(err)
+	}
+	return
+}
+
+// ReadDir implements ftpserverlib.ClientDriverExtensionFileList interface.
+func (c *ftpfs) ReadDir(name string) (list []os.FileInfo, err error) {
+	var fullpath string
+	if fullpath, err = c.sp.fullpath(name); err != nil {
+		return nil, c.err(err)
+	}
+	var ents []fs.DirEntry
+	if ents, err = c.sp.JointPool.ReadDir(fullpath); err != nil {
+		return nil, c.err(err)
+	}
+	list = make([]os.FileInfo, 0, len(ents))
+	for _, de := range ents {
+		if fi, err := de.Info(); err == nil {
+			list = append(list, fi)
+		}
+	}
+	return list, nil
+}
+
+func (c *ftpfs) Mkdir(name string, perm os.FileMode) error {
+	return c.write(name, c.sp.JointPool.Mkdir)
+}
+
+func (c *ftpfs) MkdirAll(name string, perm os.FileMode) error {
+	return errors.ErrUnsupported
+}
+
+// remove removes file or directory, given isdir should match it.
+func (c *ftpfs) remove(name string, isdir bool) error {
+	return c.write(name, func(fullpath string) error {
+		var fi, err = c.sp.JointPool.Lstat(fullpath)
+		if err != nil {
+			return err
+		}
+		if isrealdir(ToDirEntry(fi)) != isdir {
+			if isdir {
+				return errFtpNotDir
+			}
+			return errFtpNotFile
+		}
+		return c.sp.JointPool.Remove(fullpath)
+	})
+}
+
+func (c *ftpfs) Remove(name string) error {
+	return c.remove(name, false)
+}
+
+// RemoveDir implements ftpserverlib.ClientDriverExtensionRemoveDir interface.
+func (c *ftpfs) RemoveDir(name string) error {
+	return c.remove(name, true)
+}
+
+func (c *ftpfs) RemoveAll(name string) error {
+	return c.write(name, c.sp.JointPool.RemoveAll)
+}
+
+func (c *ftpfs) Rename(oldname, newname string) error {
+	return c.write(oldname, func(oldpath string) error {
+		var newpath, err = c.sp.fullpath(newname)
+		if err != nil {
+			return err
+		}
+		return c.sp.JointPool.Rename(oldpath, newpath)
+	})
+}
+
+// GetHandle implements ftpserverlib.ClientDriverExtentionFileTransfer
+// interface. Containers are downloaded as files.
+func (c *ftpfs) GetHandle(name string, flags int, offset int64) (ftpserver.FileTransfer, error) {
+	if flags&(os.O_WRONLY|os.O_RDWR) != 0 {
+		return c.puthandle(name, flags, offset)
+	}
+	var fullpath, err = c.sp.fullpath(name)
+	if err != nil {
+		return nil, c.err(err)
+	}
+	var fi fs.FileInfo
+	if fi, err = c.sp.JointPool.Stat(fullpath); err != nil {
+		return nil, c.err(err)
+	}
+	if isrealdir(ToDirEntry(fi)) {
+		return nil, errFtpNotFile
+	}
+	var jw, fpath, err1 = c.sp.JointPool.getjoint(fullpath, len(fullpath)-1)
+	if err1 != nil {
+		return nil, c.err(err1)
+	}
+	if _, err = jw.Open(fpath); err != nil {
+		release(jw, err)
+		return nil, c.err(err)
+	}
+	return &ftpreader{c: c, jw: jw}, nil
+}
+
+// puthandle returns handle to upload file from given offset.
+func (c *ftpfs) puthandle(name string, flags int, offset int64) (ft ftpserver.FileTransfer, err error) {
+	err = c.write(name, func(fullpath string) error {
+		var fi, err = c.sp.JointPool.Lstat(fullpath)
+		if err == nil {
+			if isrealdir(ToDirEntry(fi)) {
+				return errFtpNotFile
+			}
+			if flags&os.O_APPEND != 0 {
+				offset = fi.Size()
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		var wc io.WriteCloser
+		if wc, err = c.sp.JointPool.create(fullpath, offset); err != nil {
+			return err
+		}
+		ft = &ftpwriter{c: c, wc: wc, off: offset}
+		return nil
+	})
+	return
+}
+
+// ftpreader is handle of file downloaded from opened joint.
+type ftpreader struct {
+	c   *ftpfs
+	jw  JointWrap
+	err error // failure of joint
+}
+
+func (r *ftpreader) Read(b []byte) (n int, err error) {
+	if n, err = r.jw.Read(b); err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, r.c.err(err)
+}
+
+func (r *ftpreader) Seek(offset int64, whence int) (pos int64, err error) {
+	if pos, err = r.jw.Seek(offset, whence); err != nil {
+		r.err = err
+	}
+	return pos, r.c.err(err)
+}
+
+func (r *ftpreader) Write([]byte) (int, error) {
+	return 0, fs.ErrPermission
+}
+
+func (r *ftpreader) Close() error {
+	release(r.jw, r.err)
+	return nil
+}
+
+// ftpwriter is handle of file uploaded from given offset.
+type ftpwriter struct {
+	c   *ftpfs
+	wc  io.WriteCloser
+	off int64
+}
+
+func (w *ftpwriter) Write(b []byte) (n int, err error) {
+	n, err = w.wc.Write(b)
+	w.off += int64(n)
+	return n, w.c.err(err)
+}
+
+// Seek is called to position at offset of resumed upload,
+// writer is already placed there.
+func (w *ftpwriter) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekStart || offset != w.off {
+		return 0, errors.ErrUnsupported
+	}
+	return w.off, nil
+}
+
+func (w *ftpwriter) Read([]byte) (int, error) {
+	return 0, fs.ErrPermission
+}
+
+func (w *ftpwriter) Close() error {
+	return w.c.err(w.wc.Close())
+}
@@ -0,0 +1,234 @@
+package joint_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// startFtp starts FTP-server at local host with read-only user
+// "reader" at testdata directory, and read-write user "writer"
+// at given directory. Returns address of server.
+func startFtp(t *testing.T, root string) string {
+	var l, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cwd, _ = os.Getwd()
+	var srv = jnt.NewFtpServer(nil, jnt.ServerUsers{
+		"reader": {Password: "pass", Root: filepath.ToSlash(filepath.Join(cwd, "testdata"))},
+		"writer": {Password: "pass", Root: root, Writable: true},
+	})
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return l.Addr().String()
+}
+
+func TestFtpServer(t *testing.T) {
+	var addr = startFtp(t, filepath.ToSlash(t.TempDir()))
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	// read content of container by FTP-joint
+	var list, err = jp.ReadDir("ftp://reader:pass@" + addr + "/external.iso/data/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("number of files does not match: %v", list)
+	}
+	var f fs.File
+	if f, err = jp.Open("ftp://reader:pass@" + addr + "/external.iso/disk/internal.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	var b []byte
+	b, err = io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != foxsize {
+		t.Fatal("size of file does not match")
+	}
+
+	// container is downloaded as file
+	var orig []byte
+	if orig, err = os.ReadFile("testdata/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+	var conn *ftp.ServerConn
+	if conn, err = ftp.Dial(addr); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Quit()
+	if err = conn.Login("reader", "pass"); err != nil {
+		t.Fatal(err)
+	}
+	var resp *ftp.Response
+	if resp, err = conn.RetrFrom("external.iso", 1000); err != nil {
+		t.Fatal(err)
+	}
+	b, err = io.ReadAll(resp)
+	resp.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, orig[1000:]) {
+		t.Fatal("content of container does not match")
+	}
+	if err = conn.MakeDir("dir"); err == nil {
+		t.Fatal("read-only user should not create directory")
+	}
+	if err = conn.ChangeDir("../.."); err != nil {
+		t.Fatal(err)
+	}
+	var wd string
+	if wd, err = conn.CurrentDir(); err != nil {
+		t.Fatal(err)
+	}
+	if wd != "/" {
+		t.Fatal("user should not leave root directory")
+	}
+	if err = conn.Login("reader", "wrong"); err == nil {
+		t.Fatal("login with wrong password should fail")
+	}
+}
+
+func TestFtpServerWrite(t *testing.T) {
+	var root = filepath.ToSlash(t.TempDir())
+	var addr = startFtp(t, root)
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+	var base = "ftp://writer:pass@" + addr + "/"
+
+	if err := jp.Mkdir(base + "dir"); err != nil {
+		t.Fatal(err)
+	}
+	if err := jp.Copy(context.Background(), "testdata/external.iso/disk/internal.iso/fox.txt", base+"dir/fox.txt", jnt.CopyOptions{
+		PreserveMtime: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var fi, err = os.Stat(root + "/dir/fox.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != foxsize {
+		t.Fatal("size of uploaded file does not match")
+	}
+	if err = jp.Rename(base+"dir/fox.txt", base+"fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(root + "/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err = jp.RemoveAll(base + "dir"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(root + "/dir"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("directory should be removed")
+	}
+}
+
+// Check that passive data connection from another host is rejected.
+func TestFtpServerForeignData(t *testing.T) {
+	var addr = startFtp(t, filepath.ToSlash(t.TempDir()))
+	var c, err = textproto.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var cmd = func(code int, format string, args ...any) (msg string) {
+		if format != "" {
+			if _, err = c.Cmd(format, args...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, msg, err = c.ReadResponse(code); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	cmd(220, "")
+	cmd(331, "USER reader")
+	cmd(230, "PASS pass")
+	var port int
+	if _, err = fmt.Sscanf(cmd(229, "EPSV"), "Entering Extended Passive Mode (|||%d|)", &port); err != nil {
+		t.Fatal(err)
+	}
+	var data = net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+
+	// loopback address other than address of control connection
+	var d = net.Dialer{LocalAddr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 2)}}
+	var foreign net.Conn
+	if foreign, err = d.Dial("tcp", data); err != nil {
+		t.Skipf("can not connect from another address: %v", err)
+	}
+	defer foreign.Close()
+
+	// transfer is rejected, nothing is sent to foreign connection
+	cmd(425, "NLST")
+	foreign.SetReadDeadline(time.Now().Add(time.Second))
+	var b []byte
+	if b, _ = io.ReadAll(foreign); len(b) > 0 {
+		t.Fatal("listing is sent to foreign data connection")
+	}
+}
+
+func TestFtpServerLimits(t *testing.T) {
+	var users = jnt.ServerUsers{
+		"reader": {Password: "pass", Root: filepath.ToSlash(t.TempDir())},
+	}
+	var srv = jnt.NewFtpServer(nil, users)
+	srv.IdleTimeout = 2 * time.Second
+	var l, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	// wait reply 220 and then closing of connection
+	var wait = func(f func(c *textproto.Conn)) time.Duration {
+		var conn net.Conn
+		if conn, err = net.Dial("tcp", l.Addr().String()); err != nil {
+			t.Fatal(err)
+		}
+		var c = textproto.NewConn(conn)
+		defer c.Close()
+		if _, _, err = c.ReadResponse(220); err != nil {
+			t.Fatal(err)
+		}
+		f(c)
+		var start = time.Now()
+		conn.SetReadDeadline(start.Add(10 * time.Second))
+		for err == nil {
+			_, err = c.ReadLine()
+		}
+		return time.Since(start)
+	}
+
+	if d := wait(func(c *textproto.Conn) {
+		c.W.WriteString(strings.Repeat("A", 8*1024))
+		c.W.Flush()
+	}); d >= time.Second {
+		t.Fatalf("connection with too long line is closed after %s", d)
+	}
+	if d := wait(func(c *textproto.Conn) {}); d < time.Second || d > 5*time.Second {
+		t.Fatalf("idle connection is closed after %s", d)
+	}
+}
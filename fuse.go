@@ -0,0 +1,413 @@
+//go:build linux
+
+package joint
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	gofs "github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// DefMountTimeout is default time while kernel caches
+// attributes and names of files at mounted SubPool.
+const DefMountTimeout = time.Second
+
+// MountOptions determines how Mount works.
+type MountOptions struct {
+	// Allow to create, rewrite, rename and remove files
+	// at writable joints. Mount is read-only otherwise.
+	Writable bool
+	// Time while kernel caches attributes and names of files.
+	// The pool has no own cache of files infos, so it's the time
+	// while changes at remote resources can be unnoticed.
+	// DefMountTimeout is used if it's zero, and caching
+	// is disabled if it's negative.
+	AttrTimeout time.Duration
+	// Allow access to other users, it requires
+	// user_allow_other option at /etc/fuse.conf.
+	AllowOther bool
+	// Print FUSE requests to log.
+	Debug bool
+}
+
+// Mount mounts SubPool as FUSE file system at given directory.
+// Containers are represented as directories, files are read by
+// ReadAt calls on joints taken from pool caches, so the opened
+// files share connections with other pool users. Files can be only
+// written sequentially from the beginning, existing files can be only
+// rewritten after truncation, and files are completed on close.
+// Attributes are cached only by kernel for AttrTimeout, they are not
+// shared with other pool users. Returned server should be unmounted
+// by Unmount call.
+func Mount(sp *SubPool, dir string, opts MountOptions) (*fuse.Server, error) {
+	if opts.AttrTimeout == 0 {
+		opts.AttrTimeout = DefMountTimeout
+	} else if opts.AttrTimeout < 0 {
+		opts.AttrTimeout = 0
+	}
+	var root = &fusenode{
+		sp:    sp,
+		fpath: sp.dir,
+		opts:  &opts,
+	}
+	return gofs.Mount(dir, root, &gofs.Options{
+		MountOptions: fuse.MountOptions{
+			AllowOther: opts.AllowOther,
+			Debug:      opts.Debug,
+			FsName:     sp.dir,
+			Name:       "joint",
+			// try mount syscall before fusermount
+			DirectMount: true,
+		},
+		EntryTimeout:    &opts.AttrTimeout,
+		AttrTimeout:     &opts.AttrTimeout,
+		NegativeTimeout: &opts.AttrTimeout,
+	})
+}
+
+// fuseerrno converts error to FUSE status.
+func fuseerrno(err error) syscall.Errno {
+	var errno syscall.Errno
+	switch {
+	case err == nil:
+		return gofs.OK
+	case errors.As(err, &errno):
+		return errno
+	case errors.Is(err, fs.ErrNotExist):
+		return syscall.ENOENT
+	case errors.Is(err, fs.ErrExist):
+		return syscall.EEXIST
+	case errors.Is(err, fs.ErrPermission):
+		return syscall.EACCES
+	case errors.Is(err, ErrReadOnly):
+		return syscall.EROFS
+	case errors.Is(err, fs.ErrInvalid):
+		return syscall.EINVAL
+	case errors.Is(err, errors.ErrUnsupported), errors.Is(err, ErrWriteGap):
+		return syscall.ENOTSUP
+	}
+	return syscall.EIO
+}
+
+// fuseattr fills FUSE attributes by file info.
+func fuseattr(fi fs.FileInfo, writable bool, out *fuse.Attr) {
+	var mtime = fi.ModTime()
+	out.Size = uint64(fi.Size())
+	out.Blocks = (out.Size + 511) / 512
+	out.SetTimes(nil, &mtime, &mtime)
+	if fi.IsDir() {
+		out.Mode = syscall.S_IFDIR | 0555
+	} else {
+		out.Mode = syscall.S_IFREG | 0444
+	}
+	if writable {
+		out.Mode |= 0200
+	}
+}
+
+// fusenode is file or directory at mounted SubPool.
+type fusenode struct {
+	gofs.Inode
+	sp    *SubPool
+	fpath string // full path at the pool
+	opts  *MountOptions
+	trunc atomic.Uint64 // counter of truncations to zero size
+}
+
+var (
+	_ gofs.NodeGetattrer = (*fusenode)(nil)
+	_ gofs.NodeSetattrer = (*fusenode)(nil)
+	_ gofs.NodeLookuper  = (*fusenode)(nil)
+	_ gofs.NodeReaddirer = (*fusenode)(nil)
+	_ gofs.NodeOpener    = (*fusenode)(nil)
+	_ gofs.NodeCreater   = (*fusenode)(nil)
+	_ gofs.NodeMkdirer   = (*fusenode)(nil)
+	_ gofs.NodeUnlinker  = (*fusenode)(nil)
+	_ gofs.NodeRmdirer   = (*fusenode)(nil)
+	_ gofs.NodeRenamer   = (*fusenode)(nil)
+)
+
+// child returns new node for file with given info.
+func (n *fusenode) child(ctx context.Context, name string, fi fs.FileInfo, out *fuse.EntryOut) *gofs.Inode {
+	fuseattr(fi, n.opts.Writable, &out.Attr)
+	var mode uint32 = syscall.S_IFREG
+	if fi.IsDir() {
+		mode = syscall.S_IFDIR
+	}
+	return n.NewInode(ctx, &fusenode{
+		sp:    n.sp,
+		fpath: JoinPath(n.fpath, name),
+		opts:  n.opts,
+	}, gofs.StableAttr{Mode: mode})
+}
+
+// Getattr implements fs.NodeGetattrer interface.
+func (n *fusenode) Getattr(ctx context.Context, fh gofs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	var fi, err = n.sp.JointPool.Stat(n.fpath)
+	if err != nil {
+		return fuseerrno(err)
+	}
+	fuseattr(fi, n.opts.Writable, &out.Attr)
+	if w, ok := fh.(*fusewriter); ok { // file is written now
+		w.mux.Lock()
+		if w.wc != nil {
+			out.Size = uint64(w.off)
+		}
+		w.mux.Unlock()
+	}
+	return gofs.OK
+}
+
+// Setattr implements fs.NodeSetattrer interface. Only modification
+// time can be changed, and files can be truncated to zero size, other
+// attributes are ignored. Kernel truncates file by this call after
+// it's opened with O_TRUNC flag.
+func (n *fusenode) Setattr(ctx context.Context, fh gofs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if !n.opts.Writable {
+		return syscall.EROFS
+	}
+	if size, ok := in.GetSize(); ok {
+		var err error
+		if w, ok := fh.(*fusewriter); ok {
+			err = w.truncate(size)
+		} else if size == 0 {
+			var wc io.WriteCloser
+			if wc, err = n.sp.JointPool.Create(n.fpath); err == nil {
+				err = wc.Close()
+			}
+			if err == nil {
+				n.trunc.Add(1)
+			}
+		} else {
+			err = errors.ErrUnsupported
+		}
+		if err != nil {
+			return fuseerrno(err)
+		}
+	}
+	if mtime, ok := in.GetMTime(); ok {
+		if err := n.sp.JointPool.Chtimes(n.fpath, mtime); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+			return fuseerrno(err)
+		}
+	}
+	return n.Getattr(ctx, fh, out)
+}
+
+// Lookup implements fs.NodeLookuper interface.
+func (n *fusenode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*gofs.Inode, syscall.Errno) {
+	var fi, err = n.sp.JointPool.Stat(JoinPath(n.fpath, name))
+	if err != nil {
+		return nil, fuseerrno(err)
+	}
+	return n.child(ctx, name, fi, out), gofs.OK
+}
+
+// Readdir implements fs.NodeReaddirer interface.
+func (n *fusenode) Readdir(ctx context.Context) (gofs.DirStream, syscall.Errno) {
+	var list, err = n.sp.JointPool.ReadDir(n.fpath)
+	if err != nil {
+		return nil, fuseerrno(err)
+	}
+	var ents = make([]fuse.DirEntry, len(list))
+	for i, de := range list {
+		ents[i].Name = de.Name()
+		if de.IsDir() {
+			ents[i].Mode = syscall.S_IFDIR
+		} else {
+			ents[i].Mode = syscall.S_IFREG
+		}
+	}
+	return gofs.NewListDirStream(ents), gofs.OK
+}
+
+// Open implements fs.NodeOpener interface.
+func (n *fusenode) Open(ctx context.Context, flags uint32) (gofs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		if !n.opts.Writable {
+			return nil, 0, syscall.EROFS
+		}
+		if flags&syscall.O_APPEND != 0 {
+			return nil, 0, syscall.ENOTSUP
+		}
+		// file is rewritten after truncation
+		return &fusewriter{node: &n.Inode, sp: n.sp, fpath: n.fpath,
+			trunc: &n.trunc, gen: n.trunc.Load()}, fuse.FOPEN_DIRECT_IO, gofs.OK
+	}
+	// containers are read as files
+	var jw, fpath, err = n.sp.JointPool.getjoint(n.fpath, len(n.fpath)-1)
+	if err != nil {
+		return nil, 0, fuseerrno(err)
+	}
+	if _, err = jw.Open(fpath); err != nil {
+		release(jw, err)
+		return nil, 0, fuseerrno(err)
+	}
+	return &fusereader{jointreader{jw: jw}}, 0, gofs.OK
+}
+
+// Create implements fs.NodeCreater interface.
+func (n *fusenode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*gofs.Inode, gofs.FileHandle, uint32, syscall.Errno) {
+	if !n.opts.Writable {
+		return nil, nil, 0, syscall.EROFS
+	}
+	var fpath = JoinPath(n.fpath, name)
+	var wc, err = n.sp.JointPool.Create(fpath)
+	if err != nil {
+		return nil, nil, 0, fuseerrno(err)
+	}
+	var fi = putinfo{name: name, mtime: time.Now()}
+	var node = n.child(ctx, name, fi, out)
+	return node, &fusewriter{seqwriter: seqwriter{wc: wc}, node: node}, fuse.FOPEN_DIRECT_IO, gofs.OK
+}
+
+// Mkdir implements fs.NodeMkdirer interface.
+func (n *fusenode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*gofs.Inode, syscall.Errno) {
+	if !n.opts.Writable {
+		return nil, syscall.EROFS
+	}
+	var fpath = JoinPath(n.fpath, name)
+	if err := n.sp.JointPool.Mkdir(fpath); err != nil {
+		return nil, fuseerrno(err)
+	}
+	var fi, err = n.sp.JointPool.Stat(fpath)
+	if err != nil {
+		return nil, fuseerrno(err)
+	}
+	return n.child(ctx, name, fi, out), gofs.OK
+}
+
+// Unlink implements fs.NodeUnlinker interface.
+func (n *fusenode) Unlink(ctx context.Context, name string) syscall.Errno {
+	if !n.opts.Writable {
+		return syscall.EROFS
+	}
+	return fuseerrno(n.sp.JointPool.Remove(JoinPath(n.fpath, name)))
+}
+
+// Rmdir implements fs.NodeRmdirer interface.
+func (n *fusenode) Rmdir(ctx context.Context, name string) syscall.Errno {
+	if !n.opts.Writable {
+		return syscall.EROFS
+	}
+	return fuseerrno(n.sp.JointPool.Remove(JoinPath(n.fpath, name)))
+}
+
+// Rename implements fs.NodeRenamer interface.
+func (n *fusenode) Rename(ctx context.Context, name string, newParent gofs.InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	if !n.opts.Writable {
+		return syscall.EROFS
+	}
+	if flags != 0 { // RENAME_EXCHANGE and RENAME_NOREPLACE
+		return syscall.ENOTSUP
+	}
+	var np, ok = newParent.(*fusenode)
+	if !ok {
+		return syscall.EXDEV
+	}
+	return fuseerrno(n.sp.JointPool.Rename(JoinPath(n.fpath, name), JoinPath(np.fpath, newName)))
+}
+
+// fusereader is file opened for reading.
+type fusereader struct {
+	jointreader
+}
+
+var (
+	_ gofs.FileReader   = (*fusereader)(nil)
+	_ gofs.FileReleaser = (*fusereader)(nil)
+)
+
+// Read implements fs.FileReader interface.
+func (r *fusereader) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	var n, err = r.ReadAt(dest, off)
+	if err != nil && err != io.EOF {
+		return nil, fuseerrno(err)
+	}
+	return fuse.ReadResultData(dest[:n]), gofs.OK
+}
+
+// Release implements fs.FileReleaser interface.
+func (r *fusereader) Release(ctx context.Context) syscall.Errno {
+	return fuseerrno(r.Close())
+}
+
+// fusewriter is file opened for writing. Existing file opened
+// without creation is rewritten after truncation to zero size,
+// its writing is not supported otherwise.
+type fusewriter struct {
+	seqwriter
+	node  *gofs.Inode
+	sp    *SubPool
+	fpath string         // full path of existing file
+	trunc *atomic.Uint64 // counter of truncations of the file by path
+	gen   uint64         // value of truncations counter on opening
+}
+
+var (
+	_ gofs.FileWriter   = (*fusewriter)(nil)
+	_ gofs.FileFlusher  = (*fusewriter)(nil)
+	_ gofs.FileReleaser = (*fusewriter)(nil)
+)
+
+// truncate changes size of the file opened for writing.
+func (w *fusewriter) truncate(size uint64) (err error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.wc == nil && size == 0 && !w.closed {
+		w.wc, err = w.sp.JointPool.Create(w.fpath)
+		return
+	}
+	if w.wc == nil || uint64(w.off) != size {
+		return errors.ErrUnsupported
+	}
+	return
+}
+
+// start begins rewriting of existing file if it was truncated
+// after opening. Kernel truncates it by path on O_TRUNC flag.
+func (w *fusewriter) start() (err error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.wc != nil || w.closed {
+		return
+	}
+	if w.trunc.Load() == w.gen {
+		return errors.ErrUnsupported
+	}
+	w.wc, err = w.sp.JointPool.Create(w.fpath)
+	return
+}
+
+// Write implements fs.FileWriter interface.
+func (w *fusewriter) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	if err := w.start(); err != nil {
+		return 0, fuseerrno(err)
+	}
+	var n, err = w.WriteAt(data, off)
+	return uint32(n), fuseerrno(err)
+}
+
+// Flush implements fs.FileFlusher interface. File is completed on
+// first flush, so error of writing is returned to close call.
+func (w *fusewriter) Flush(ctx context.Context) syscall.Errno {
+	var err = w.Close()
+	// written file can be container, so kernel should
+	// look it up again to get it as directory
+	if name, parent := w.node.Parent(); parent != nil {
+		go parent.NotifyEntry(name)
+	}
+	return fuseerrno(err)
+}
+
+// Release implements fs.FileReleaser interface.
+func (w *fusewriter) Release(ctx context.Context) syscall.Errno {
+	w.Close()
+	return gofs.OK
+}
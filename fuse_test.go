@@ -0,0 +1,131 @@
+//go:build linux
+
+package joint_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+func TestMount(t *testing.T) {
+	var sp = jnt.NewSubPool(nil, "testdata")
+	defer sp.Close()
+	var dir = t.TempDir()
+	var srv, err = jnt.Mount(sp, dir, jnt.MountOptions{})
+	if err != nil {
+		t.Skipf("FUSE is not available: %v", err)
+	}
+	defer srv.Unmount()
+
+	var list []os.DirEntry
+	if list, err = os.ReadDir(dir + "/external.iso/data/docs"); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("number of files does not match: %v", list)
+	}
+	var b []byte
+	if b, err = os.ReadFile(dir + "/external.iso/disk/internal.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != foxsize {
+		t.Fatal("size of file does not match")
+	}
+	if err = os.Mkdir(dir+"/dir", 0755); err == nil {
+		t.Fatal("read-only file system should not be modified")
+	}
+}
+
+func TestMountWrite(t *testing.T) {
+	var root = t.TempDir()
+	var sp = jnt.NewSubPool(nil, root)
+	defer sp.Close()
+	var dir = t.TempDir()
+	var srv, err = jnt.Mount(sp, dir, jnt.MountOptions{Writable: true, AttrTimeout: -1})
+	if err != nil {
+		t.Skipf("FUSE is not available: %v", err)
+	}
+	defer srv.Unmount()
+
+	var orig []byte
+	if orig, err = os.ReadFile("testdata/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Mkdir(dir+"/dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(dir+"/dir/external.iso", orig, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var b []byte
+	if b, err = os.ReadFile(root + "/dir/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, orig) {
+		t.Fatal("content of written file does not match")
+	}
+	if err = os.Rename(dir+"/dir/external.iso", dir+"/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Remove(dir + "/dir"); err != nil {
+		t.Fatal(err)
+	}
+	var list []os.DirEntry
+	if list, err = os.ReadDir(dir + "/external.iso/data/docs"); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("number of files does not match: %v", list)
+	}
+}
+
+func TestMountRewrite(t *testing.T) {
+	var root = t.TempDir()
+	var sp = jnt.NewSubPool(nil, root)
+	defer sp.Close()
+	var dir = t.TempDir()
+	var srv, err = jnt.Mount(sp, dir, jnt.MountOptions{Writable: true, AttrTimeout: -1})
+	if err != nil {
+		t.Skipf("FUSE is not available: %v", err)
+	}
+	defer srv.Unmount()
+
+	if err = os.WriteFile(root+"/fox.txt", []byte("The quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// existing file can not be changed without truncation
+	var f *os.File
+	if f, err = os.OpenFile(dir+"/fox.txt", os.O_WRONLY, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.Write([]byte("The lazy")); !errors.Is(err, syscall.ENOTSUP) {
+		f.Close()
+		t.Fatalf("existing file should not be written without truncation, got %v", err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var b []byte
+	if b, err = os.ReadFile(root + "/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "The quick brown fox" {
+		t.Fatal("content of existing file is changed")
+	}
+
+	// existing file is rewritten with truncation
+	if err = os.WriteFile(dir+"/fox.txt", []byte("jumps over the lazy dog"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = os.ReadFile(root + "/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "jumps over the lazy dog" {
+		t.Fatal("content of rewritten file does not match")
+	}
+}
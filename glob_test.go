@@ -0,0 +1,112 @@
+package joint_test
+
+import (
+	"io/fs"
+	"path"
+	"reflect"
+	"testing"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+func TestGlob(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var matches, err = jp.Glob("testdata/*.iso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matches, []string{"testdata/external.iso", "testdata/links.iso"}) {
+		t.Fatalf("unexpected matches %q", matches)
+	}
+
+	if matches, err = jp.Glob("testdata/*.iso/**/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matches, []string{"testdata/external.iso/fox.txt", "testdata/links.iso/fox.txt"}) {
+		t.Fatalf("unexpected matches %q", matches)
+	}
+
+	if matches, err = jp.Glob("testdata/external.iso/*/*.iso/d?cs/*"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matches, []string{
+		"testdata/external.iso/disk/internal.iso/docs/doc1.txt",
+		"testdata/external.iso/disk/internal.iso/docs/doc2.txt",
+	}) {
+		t.Fatalf("unexpected matches %q", matches)
+	}
+
+	if _, err = jp.Glob("testdata/[*.iso"); err != path.ErrBadPattern {
+		t.Fatal("expected bad pattern error")
+	}
+}
+
+func TestFind(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var matches, err = jp.Find("testdata/*.iso/**/fox.txt", jnt.FindOptions{Containers: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matches, []string{
+		"testdata/external.iso/fox.txt",
+		"testdata/external.iso/disk/internal.iso/fox.txt",
+		"testdata/links.iso/fox.txt",
+	}) {
+		t.Fatalf("unexpected matches %q", matches)
+	}
+
+	if matches, err = jp.Find("testdata/archive.zip/**", jnt.FindOptions{
+		Type:    jnt.FindFiles,
+		MinSize: 1000,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matches, []string{
+		"testdata/archive.zip", // archive itself is not a real directory
+		"testdata/archive.zip/data/lorem1.txt",
+		"testdata/archive.zip/data/доки/док2.txt",
+		"testdata/archive.zip/data/рыба.txt",
+	}) {
+		t.Fatalf("unexpected matches %q", matches)
+	}
+
+	if matches, err = jp.Find("testdata/archive.zip/**", jnt.FindOptions{
+		Type: jnt.FindDirs,
+		Filter: func(fpath string, fi fs.FileInfo) bool {
+			return fi.Name() != "docs"
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matches, []string{
+		"testdata/archive.zip",
+		"testdata/archive.zip/data",
+		"testdata/archive.zip/data/доки",
+	}) {
+		t.Fatalf("unexpected matches %q", matches)
+	}
+}
+
+func TestSubGlob(t *testing.T) {
+	var sp = jnt.NewSubPool(nil, "testdata/archive.zip")
+	defer sp.Close()
+
+	var matches, err = fs.Glob(sp, "data/*/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matches, []string{"data/docs/doc1.txt", "data/доки/док2.txt"}) {
+		t.Fatalf("unexpected matches %q", matches)
+	}
+
+	if matches, err = sp.Glob("**/doc*"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matches, []string{"data/docs", "data/docs/doc1.txt"}) {
+		t.Fatalf("unexpected matches %q", matches)
+	}
+}
@@ -3,17 +3,42 @@ module github.com/schwarzlichtbezirk/joint
 go 1.21
 
 require (
+	github.com/bodgit/sevenzip v1.6.0
+	github.com/fclairamb/ftpserverlib v0.25.0
+	github.com/fclairamb/go-log v0.5.0
+	github.com/hanwen/go-fuse/v2 v2.9.0
+	github.com/hirochachacha/go-smb2 v1.1.0
 	github.com/jlaffaye/ftp v0.2.0
 	github.com/kdomanski/iso9660 v0.4.0
+	github.com/minio/minio-go/v7 v7.0.77
+	github.com/nwaples/rardecode/v2 v2.4.1
 	github.com/pkg/sftp v1.13.6
+	github.com/spf13/afero v1.11.0
 	github.com/studio-b12/gowebdav v0.9.0
-	golang.org/x/crypto v0.19.0
-	golang.org/x/text v0.14.0
+	golang.org/x/crypto v0.26.0
+	golang.org/x/net v0.28.0
+	golang.org/x/text v0.20.0
 )
 
 require (
+	github.com/andybalholm/brotli v1.1.1 // indirect
+	github.com/bodgit/plumbing v1.3.0 // indirect
+	github.com/bodgit/windows v1.0.1 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/geoffgarside/ber v1.1.0 // indirect
+	github.com/go-ini/ini v1.67.0 // indirect
+	github.com/goccy/go-json v0.10.3 // indirect
+	github.com/google/uuid v1.6.0 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
+	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/klauspost/cpuid/v2 v2.2.8 // indirect
 	github.com/kr/fs v0.1.0 // indirect
-	golang.org/x/sys v0.17.0 // indirect
+	github.com/minio/md5-simd v1.1.2 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	github.com/rs/xid v1.6.0 // indirect
+	github.com/ulikunitz/xz v0.5.12 // indirect
+	go4.org v0.0.0-20200411211856-f5505b9728dd // indirect
+	golang.org/x/sys v0.28.0 // indirect
 )
@@ -0,0 +1,194 @@
+package joint_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// Sample of nginx autoindex page.
+const nginxindex = `<html>
+<head><title>Index of /distro/</title></head>
+<body>
+<h1>Index of /distro/</h1><hr><pre><a href="../">../</a>
+<a href="boot/">boot/</a>                                              18-Oct-2024 10:12                   -
+<a href="disk%201.iso">disk 1.iso</a>                                        18-Oct-2024 10:15             1212416
+<a href="readme.txt">readme.txt</a>                                         17-Oct-2024 09:01                  44
+</pre><hr></body>
+</html>`
+
+func TestParseAutoindex(t *testing.T) {
+	var list = jnt.ParseAutoindex(nginxindex)
+	if len(list) != 3 {
+		t.Fatalf("expected 3 files in autoindex, found %d files", len(list))
+	}
+	if list[0].Name() != "boot" || !list[0].IsRealDir() {
+		t.Fatal("expected 'boot' directory")
+	}
+	if list[1].Name() != "disk 1.iso" || list[1].IsRealDir() || !list[1].IsDir() {
+		t.Fatal("expected 'disk 1.iso' file as virtual folder")
+	}
+	if list[1].Size() != 1212416 {
+		t.Fatal("size of 'disk 1.iso' does not match")
+	}
+	if list[2].Name() != "readme.txt" || list[2].ModTime().Day() != 17 {
+		t.Fatal("expected 'readme.txt' file with valid time")
+	}
+}
+
+func TestHttpJoint(t *testing.T) {
+	var err error
+
+	var srv = httptest.NewServer(http.FileServer(http.Dir(".")))
+	defer srv.Close()
+
+	var j = &jnt.HttpJoint{}
+	if err = j.Make(nil, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	defer j.Cleanup()
+
+	var fi fs.FileInfo
+	if fi, err = j.Info("testdata/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+
+	if fi.Size() != isosize {
+		t.Fatal("ISO-file size does not match")
+	}
+
+	if err = checkReadDir(j); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Check file reading in internal ISO-disk placed at HTTP-server.
+func TestHttpIntReadFile(t *testing.T) {
+	var err error
+
+	var srv = httptest.NewServer(http.FileServer(http.Dir(".")))
+	defer srv.Close()
+
+	var j1 jnt.Joint = &jnt.HttpJoint{}
+	if err = j1.Make(nil, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	var j2 jnt.Joint = &jnt.IsoJoint{}
+	if err = j2.Make(j1, "testdata/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+
+	var j3 jnt.Joint = &jnt.IsoJoint{}
+	if err = j3.Make(j2, "disk/internal.iso"); err != nil {
+		t.Fatal(err)
+	}
+	defer j3.Cleanup() // only top-level joint must be called for Cleanup
+
+	for _, fpath := range intfiles {
+		if err = checkFile(j3, fpath); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Read files chunks on HttpJoint.
+func TestHttpReadChunk(t *testing.T) {
+	var err error
+
+	var srv = httptest.NewServer(http.FileServer(http.Dir(".")))
+	defer srv.Close()
+
+	var j1 jnt.Joint = &jnt.HttpJoint{}
+	if err = j1.Make(nil, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	defer j1.Cleanup()
+
+	var j2 jnt.Joint = &jnt.IsoJoint{}
+	if err = readChunk(j2, j1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Check that pool falls back to HTTP joint for server without WebDAV.
+func TestHttpPoolOpen(t *testing.T) {
+	var err error
+
+	var srv = httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var list []fs.DirEntry
+	if list, err = jp.ReadDir(srv.URL + "/external.iso/disk/"); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name() != "internal.iso" {
+		t.Fatal("expected 'internal.iso' file in 'disk' directory")
+	}
+
+	var f fs.File
+	if f, err = jp.Open(srv.URL + "/external.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var b []byte
+	if b, err = io.ReadAll(f); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "The quick brown fox jumps over the lazy dog." {
+		t.Fatal("read string does not match to pattern")
+	}
+}
+
+// Check that pool opens nested paths on server without WebDAV,
+// and that server is not probed for WebDAV again.
+func TestHttpPoolNested(t *testing.T) {
+	var propfind atomic.Int32
+	var fsrv = http.FileServer(http.Dir("."))
+	var srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PROPFIND" {
+			propfind.Add(1)
+		}
+		fsrv.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var done = make(chan error, 1)
+	go func() {
+		var b, err = fs.ReadFile(jp, srv.URL+"/testdata/external.iso/fox.txt")
+		if err == nil && string(b) != "The quick brown fox jumps over the lazy dog." {
+			err = errors.New("read string does not match to pattern")
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("opening of nested path does not return")
+	}
+
+	var n = propfind.Load()
+	if _, err := jp.Stat(srv.URL + "/testdata/external.iso/disk/internal.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if propfind.Load() != n {
+		t.Fatal("server without WebDAV is probed again")
+	}
+}
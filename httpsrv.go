@@ -0,0 +1,382 @@
+package joint
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HttpEntry describes file at JSON directory listing.
+type HttpEntry struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mtime"`
+	Dir       bool      `json:"dir,omitempty"`       // real directory
+	Container bool      `json:"container,omitempty"` // file that can be browsed as directory
+}
+
+// HttpHandler serves files of SubPool by HTTP. Unlike http.FileServer
+// it takes sizes and modification times of files from file infos, so
+// it does not seek files to the end, that is expensive for FTP and
+// other network joints. Files are served with Range and If-Range support
+// and stable entity tags. Directories and containers are listed in HTML
+// or JSON format. HTML listing is compatible with nginx autoindex page,
+// so it can be browsed by HttpJoint. Directories can be downloaded as
+// zip or tar archive created on the fly.
+//
+// URL paths ending with slash point to directories, containers
+// without trailing slash in the URL are served as files.
+//
+// Query parameters for directories:
+//
+//	format=json      - list directory in JSON format, it's also used
+//	                   if Accept header prefers application/json
+//	sort=name|size|time - sort listing, directories are always first
+//	order=asc|desc   - order of sorting
+//	archive=zip|tar  - download directory with all nested files
+type HttpHandler struct {
+	sp     *SubPool
+	prefix string
+	// Disable downloading of directories as archives.
+	NoArchive bool
+}
+
+// NewHttpHandler returns HTTP-handler for given subsystem.
+// Prefix is URL path prefix stripped from requests.
+func NewHttpHandler(sp *SubPool, prefix string) *HttpHandler {
+	return &HttpHandler{
+		sp:     sp,
+		prefix: prefix,
+	}
+}
+
+// httperr writes HTTP-status for given error.
+func httperr(w http.ResponseWriter, err error) {
+	var code = http.StatusInternalServerError
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		code = http.StatusNotFound
+	case errors.Is(err, fs.ErrPermission):
+		code = http.StatusForbidden
+	case errors.Is(err, fs.ErrInvalid):
+		code = http.StatusBadRequest
+	}
+	http.Error(w, http.StatusText(code), code)
+}
+
+// HttpETag returns entity tag of file for HTTP-headers. It's entity
+// tag provided by backend if it's present, or tag made from size and
+// modification time of file otherwise.
+func HttpETag(fi fs.FileInfo) string {
+	var etag = FileETag(fi)
+	if etag == "" {
+		return fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size())
+	}
+	if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = `"` + etag + `"`
+	}
+	return etag
+}
+
+// ServeHTTP implements http.Handler interface.
+func (h *HttpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	var upath, ok = strings.CutPrefix(r.URL.Path, h.prefix)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	var fullpath, err = h.sp.fullpath(upath)
+	if err != nil {
+		httperr(w, err)
+		return
+	}
+	var fi fs.FileInfo
+	if fi, err = h.sp.JointPool.StatContext(r.Context(), fullpath); err != nil {
+		httperr(w, err)
+		return
+	}
+
+	var isdir = strings.HasSuffix(upath, "/") || upath == ""
+	if isdir && !fi.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	if !isdir && isrealdir(ToDirEntry(fi)) {
+		var u = *r.URL
+		u.Path += "/"
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+		return
+	}
+	if !isdir {
+		h.serveFile(w, r, fullpath, fi)
+		return
+	}
+	switch r.URL.Query().Get("archive") {
+	case "":
+		h.serveList(w, r, fullpath)
+	case "zip", "tar":
+		if h.NoArchive {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		h.serveArchive(w, r, fullpath, fi)
+	default:
+		http.Error(w, "unknown archive format", http.StatusBadRequest)
+	}
+}
+
+// serveFile serves file content, containers are served as files.
+func (h *HttpHandler) serveFile(w http.ResponseWriter, r *http.Request, fullpath string, fi fs.FileInfo) {
+	var jw, fpath, err = h.sp.JointPool.getjoint(fullpath, len(fullpath)-1)
+	if err != nil {
+		httperr(w, err)
+		return
+	}
+	defer func() { release(jw, err) }()
+	if _, err = jw.Open(fpath); err != nil {
+		httperr(w, err)
+		return
+	}
+
+	// set content type to prevent content sniffing
+	var ctype = mime.TypeByExtension(path.Ext(fi.Name()))
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("Etag", HttpETag(fi))
+	// section reader seeks without calls to the joint
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), io.NewSectionReader(jw, 0, fi.Size()))
+}
+
+// readlist returns sorted list of directory by query parameters.
+func (h *HttpHandler) readlist(r *http.Request, fullpath string) (list []HttpEntry, err error) {
+	var des []fs.DirEntry
+	if des, err = h.sp.JointPool.ReadDirContext(r.Context(), fullpath); err != nil {
+		return
+	}
+	list = make([]HttpEntry, 0, len(des))
+	for _, de := range des {
+		var fi, err = de.Info()
+		if err != nil {
+			continue
+		}
+		var rd = isrealdir(de)
+		list = append(list, HttpEntry{
+			Name:      de.Name(),
+			Size:      fi.Size(),
+			ModTime:   fi.ModTime(),
+			Dir:       rd,
+			Container: de.IsDir() && !rd,
+		})
+	}
+
+	var q = r.URL.Query()
+	var less func(e1, e2 *HttpEntry) bool
+	switch q.Get("sort") {
+	case "size":
+		less = func(e1, e2 *HttpEntry) bool { return e1.Size < e2.Size }
+	case "time":
+		less = func(e1, e2 *HttpEntry) bool { return e1.ModTime.Before(e2.ModTime) }
+	default:
+		less = func(e1, e2 *HttpEntry) bool { return e1.Name < e2.Name }
+	}
+	var desc = q.Get("order") == "desc"
+	sort.SliceStable(list, func(i, j int) bool {
+		var e1, e2 = &list[i], &list[j]
+		if e1.Dir != e2.Dir {
+			return e1.Dir
+		}
+		if desc {
+			return less(e2, e1)
+		}
+		return less(e1, e2)
+	})
+	return
+}
+
+// serveList serves directory listing in HTML or JSON format.
+func (h *HttpHandler) serveList(w http.ResponseWriter, r *http.Request, fullpath string) {
+	var list, err = h.readlist(r, fullpath)
+	if err != nil {
+		httperr(w, err)
+		return
+	}
+	if r.URL.Query().Get("format") == "json" ||
+		strings.HasPrefix(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method == http.MethodHead {
+			return
+		}
+		json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	var title = html.EscapeString("Index of " + r.URL.Path)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<html>\n<head><title>%s</title></head>\n<body>\n<h1>%s</h1>", title, title)
+	sb.WriteString(`<p>Sort by <a href="?sort=name">name</a>, <a href="?sort=size">size</a>, <a href="?sort=time">time</a>`)
+	if !h.NoArchive {
+		sb.WriteString(`; download as <a href="?archive=zip">zip</a>, <a href="?archive=tar">tar</a>`)
+	}
+	sb.WriteString("</p><hr><pre><a href=\"../\">../</a>\n")
+	for _, e := range list {
+		var name = e.Name
+		if e.Dir || e.Container {
+			name += "/"
+		}
+		// relative reference, so name with colon is not taken as scheme
+		var href = (&url.URL{Path: "./" + name}).EscapedPath()
+		var size = "-"
+		if !e.Dir {
+			size = fmt.Sprint(e.Size)
+		}
+		var pad = 50 - len([]rune(name))
+		if pad < 1 {
+			pad = 1
+		}
+		fmt.Fprintf(&sb, "<a href=\"%s\">%s</a>%s %s %19s\n",
+			html.EscapeString(href), html.EscapeString(name), strings.Repeat(" ", pad),
+			e.ModTime.UTC().Format("02-Jan-2006 15:04"), size)
+	}
+	sb.WriteString("</pre><hr></body>\n</html>\n")
+	io.WriteString(w, sb.String())
+}
+
+// archiver writes files into zip or tar archive.
+type archiver interface {
+	dir(name string, mtime time.Time) error
+	file(name string, fi fs.FileInfo) (io.Writer, error)
+	Close() error
+}
+
+type zipper struct {
+	*zip.Writer
+}
+
+func (z zipper) dir(name string, mtime time.Time) (err error) {
+	_, err = z.CreateHeader(&zip.FileHeader{Name: name + "/", Modified: mtime})
+	return
+}
+
+func (z zipper) file(name string, fi fs.FileInfo) (io.Writer, error) {
+	return z.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: fi.ModTime()})
+}
+
+type tarrer struct {
+	*tar.Writer
+}
+
+func (t tarrer) dir(name string, mtime time.Time) error {
+	return t.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: 0755, ModTime: mtime})
+}
+
+func (t tarrer) file(name string, fi fs.FileInfo) (io.Writer, error) {
+	return t, t.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: fi.Size(), ModTime: fi.ModTime()})
+}
+
+// serveArchive streams directory with all nested files as archive.
+// Containers inside of directory are packed as files.
+func (h *HttpHandler) serveArchive(w http.ResponseWriter, r *http.Request, fullpath string, fi fs.FileInfo) {
+	var format = r.URL.Query().Get("archive")
+	var name = fi.Name()
+	if name == "" || name == "." || name == "/" {
+		name = "root"
+	}
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if format == "zip" {
+		w.Header().Set("Content-Type", "application/zip")
+	} else {
+		w.Header().Set("Content-Type", "application/x-tar")
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": name + "." + format,
+	}))
+	if r.Method == http.MethodHead {
+		return
+	}
+	var arc archiver
+	if format == "zip" {
+		arc = zipper{zip.NewWriter(w)}
+	} else {
+		arc = tarrer{tar.NewWriter(w)}
+	}
+	// errors can not be sent after response is started,
+	// so archive is left broken on error
+	if err := h.pack(r.Context(), arc, fullpath, ""); err != nil {
+		return
+	}
+	arc.Close()
+}
+
+// pack writes content of directory into archive.
+func (h *HttpHandler) pack(ctx context.Context, arc archiver, fullpath, rel string) (err error) {
+	var list []fs.DirEntry
+	if list, err = h.sp.JointPool.ReadDirContext(ctx, fullpath); err != nil {
+		return
+	}
+	for _, de := range list {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var fi fs.FileInfo
+		if fi, err = de.Info(); err != nil {
+			return
+		}
+		var fpath, name = JoinPath(fullpath, de.Name()), path.Join(rel, de.Name())
+		if isrealdir(de) {
+			if err = arc.dir(name, fi.ModTime()); err != nil {
+				return
+			}
+			if err = h.pack(ctx, arc, fpath, name); err != nil {
+				return
+			}
+			continue
+		}
+		var fw io.Writer
+		if fw, err = arc.file(name, fi); err != nil {
+			return
+		}
+		if err = h.copyfile(fw, fpath, fi.Size()); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// copyfile writes content of file to writer, containers are read as files.
+func (h *HttpHandler) copyfile(dst io.Writer, fullpath string, size int64) (err error) {
+	var jw, fpath, err1 = h.sp.JointPool.getjoint(fullpath, len(fullpath)-1)
+	if err1 != nil {
+		return err1
+	}
+	defer func() { release(jw, err) }()
+	if _, err = jw.Open(fpath); err != nil {
+		return
+	}
+	// tar requires exact size of file as it was written in header
+	_, err = io.CopyN(dst, jw.Joint, size)
+	return
+}
@@ -0,0 +1,211 @@
+package joint_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// httpget performs GET request with given headers.
+func httpget(t *testing.T, url string, hdr map[string]string) (resp *http.Response, body []byte) {
+	var req, err = http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	var client = http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	if resp, err = client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, err = io.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestHttpHandlerFile(t *testing.T) {
+	var sp = jnt.NewSubPool(nil, "testdata")
+	defer sp.Close()
+	var srv = httptest.NewServer(jnt.NewHttpHandler(sp, "/"))
+	defer srv.Close()
+	var fox = srv.URL + "/external.iso/disk/internal.iso/fox.txt"
+
+	var resp, body = httpget(t, fox, nil)
+	if resp.StatusCode != http.StatusOK || len(body) != foxsize {
+		t.Fatal("content of file does not match")
+	}
+	var etag = resp.Header.Get("Etag")
+	if etag == "" {
+		t.Fatal("entity tag is not set")
+	}
+	if resp, body = httpget(t, fox, map[string]string{"Range": "bytes=4-8"}); resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("unexpected status %d on range request", resp.StatusCode)
+	}
+	if string(body) != "quick" {
+		t.Fatalf("content of range does not match: %q", body)
+	}
+	if resp, _ = httpget(t, fox, map[string]string{"If-None-Match": etag}); resp.StatusCode != http.StatusNotModified {
+		t.Fatal("entity tag should be stable")
+	}
+	if resp, body = httpget(t, fox, map[string]string{"Range": "bytes=4-8", "If-Range": `"other"`}); resp.StatusCode != http.StatusOK || len(body) != foxsize {
+		t.Fatal("whole file should be sent for changed entity tag")
+	}
+
+	// container without slash is file
+	var orig, err = os.ReadFile("testdata/external.iso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, body = httpget(t, srv.URL+"/external.iso", map[string]string{"Range": "bytes=1000-"}); resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("unexpected status %d on range request", resp.StatusCode)
+	}
+	if !bytes.Equal(body, orig[1000:]) {
+		t.Fatal("content of container does not match")
+	}
+	if resp, _ = httpget(t, srv.URL+"/external.iso/data", nil); resp.StatusCode != http.StatusMovedPermanently {
+		t.Fatal("directory without slash should be redirected")
+	}
+}
+
+func TestHttpHandlerList(t *testing.T) {
+	var sp = jnt.NewSubPool(nil, "testdata")
+	defer sp.Close()
+	var srv = httptest.NewServer(jnt.NewHttpHandler(sp, "/"))
+	defer srv.Close()
+
+	var resp, body = httpget(t, srv.URL+"/external.iso/data/?format=json&sort=size&order=desc", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	var list []jnt.HttpEntry
+	if err := json.Unmarshal(body, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 7 {
+		t.Fatalf("number of files does not match: %v", list)
+	}
+	for i := 1; i < len(list); i++ {
+		if list[i].Dir && !list[i-1].Dir {
+			t.Fatal("directories should be first")
+		}
+		if !list[i-1].Dir && list[i].Size > list[i-1].Size {
+			t.Fatal("files should be sorted by size")
+		}
+	}
+
+	// HTML listing is compatible with autoindex
+	if resp, body = httpget(t, srv.URL+"/", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	var found bool
+	for _, fi := range jnt.ParseAutoindex(string(body)) {
+		if fi.Name() == "external.iso" {
+			found = fi.IsDir() && fi.Size() == isosize
+		}
+	}
+	if !found {
+		t.Fatal("container is not found in HTML listing")
+	}
+}
+
+func TestHttpHandlerArchive(t *testing.T) {
+	var sp = jnt.NewSubPool(nil, "testdata")
+	defer sp.Close()
+	var h = jnt.NewHttpHandler(sp, "/")
+	var srv = httptest.NewServer(h)
+	defer srv.Close()
+
+	var resp, body = httpget(t, srv.URL+"/external.iso/data/?archive=zip", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	var zr, err = zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files int
+	for _, f := range zr.File {
+		if !f.FileInfo().IsDir() {
+			files++
+		}
+		if f.Name == "docs/doc1.txt" && f.UncompressedSize64 != 445 {
+			t.Fatal("size of packed file does not match")
+		}
+	}
+	if files != 8 {
+		t.Fatalf("number of packed files does not match: %d", files)
+	}
+
+	if resp, body = httpget(t, srv.URL+"/external.iso/data/?archive=tar", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	var tr = tar.NewReader(bytes.NewReader(body))
+	files = 0
+	for {
+		var hdr *tar.Header
+		if hdr, err = tr.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			files++
+		}
+	}
+	if files != 8 {
+		t.Fatalf("number of packed files does not match: %d", files)
+	}
+
+	h.NoArchive = true
+	if resp, _ = httpget(t, srv.URL+"/external.iso/data/?archive=zip", nil); resp.StatusCode != http.StatusForbidden {
+		t.Fatal("archive should be disabled")
+	}
+}
+
+func TestHttpHandlerListColon(t *testing.T) {
+	var dir = t.TempDir()
+	for _, name := range []string{"javascript:alert(document.domain)", "c:file.txt"} {
+		if err := os.WriteFile(dir+"/"+name, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var sp = jnt.NewSubPool(nil, dir)
+	defer sp.Close()
+	var srv = httptest.NewServer(jnt.NewHttpHandler(sp, "/"))
+	defer srv.Close()
+
+	var resp, body = httpget(t, srv.URL+"/", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	for _, href := range []string{`href="javascript:`, `href="c:`} {
+		if bytes.Contains(body, []byte(href)) {
+			t.Fatalf("link is taken as URL with scheme: %s", href)
+		}
+	}
+	var list = jnt.ParseAutoindex(string(body))
+	if len(list) != 2 {
+		t.Fatalf("number of files does not match: %v", list)
+	}
+	for _, fi := range list {
+		if resp, body = httpget(t, srv.URL+"/"+url.PathEscape(fi.Name()), nil); resp.StatusCode != http.StatusOK || string(body) != "data" {
+			t.Fatalf("file %s is not served by its link", fi.Name())
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package joint
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrImgFormat = errors.New("disk image does not contains recognized file system or partitions table")
+)
+
+// Sector size of disk images.
+const imgSector = 512
+
+// imgfile is the file opened at disk image file system.
+type imgfile struct {
+	fi fs.FileInfo
+	*io.SectionReader
+}
+
+func (f *imgfile) Stat() (fs.FileInfo, error) {
+	return f.fi, nil
+}
+
+func (f *imgfile) Close() error {
+	return nil
+}
+
+// imgdir is the directory opened at disk image file system.
+type imgdir struct {
+	fi   fs.FileInfo
+	list []fs.DirEntry
+	rdn  int
+}
+
+func (d *imgdir) Stat() (fs.FileInfo, error) {
+	return d.fi, nil
+}
+
+func (d *imgdir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.fi.Name(), Err: fs.ErrInvalid}
+}
+
+func (d *imgdir) Close() error {
+	return nil
+}
+
+func (d *imgdir) ReadDir(n int) (list []fs.DirEntry, err error) {
+	if n < 0 {
+		n = len(d.list) - d.rdn
+	} else if n > len(d.list)-d.rdn {
+		n = len(d.list) - d.rdn
+		err = io.EOF
+	}
+	if n <= 0 { // on case all files readed or some deleted
+		return
+	}
+	list = d.list[d.rdn : d.rdn+n]
+	d.rdn += n
+	return
+}
+
+// OpenDiskImage recognizes file system or partitions table placed at
+// given reader, and returns FatFS, ExtFS or PartFS accordingly.
+func OpenDiskImage(r io.ReaderAt, size int64) (fs.FS, error) {
+	var head [2048]byte
+	if n, err := r.ReadAt(head[:], 0); n < len(head) && err != nil {
+		return nil, err
+	}
+	if IsExt(head[1024:]) {
+		return OpenExt(r)
+	}
+	if IsFat(head[:]) {
+		return OpenFat(r)
+	}
+	var parts, err = ReadPartitions(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return &PartFS{
+		r:     r,
+		parts: parts,
+		fsys:  make([]fs.FS, len(parts)),
+	}, nil
+}
+
+// Partition describes one partition of partitioned disk.
+type Partition struct {
+	Num    int   // partition number started from 1
+	Offset int64 // offset in bytes from disk start
+	Length int64 // length in bytes
+}
+
+// ReadPartitions reads MBR or GPT partitions table. Extended
+// MBR partitions are skipped.
+func ReadPartitions(r io.ReaderAt, size int64) (parts []Partition, err error) {
+	var le = binary.LittleEndian
+	var mbr [imgSector]byte
+	if _, err = r.ReadAt(mbr[:], 0); err != nil {
+		return
+	}
+	if mbr[510] != 0x55 || mbr[511] != 0xaa {
+		return nil, ErrImgFormat
+	}
+	for i := 0; i < 4; i++ {
+		var ent = mbr[446+i*16:]
+		switch ent[4] { // partition type
+		case 0x00, 0x05, 0x0f, 0x85: // empty or extended
+			continue
+		case 0xee: // protective MBR
+			return readgpt(r, size)
+		}
+		if ent[0] != 0x00 && ent[0] != 0x80 { // boot flag
+			return nil, ErrImgFormat
+		}
+		var p = Partition{
+			Num:    i + 1,
+			Offset: int64(le.Uint32(ent[8:])) * imgSector,
+			Length: int64(le.Uint32(ent[12:])) * imgSector,
+		}
+		if p.Length == 0 || p.Offset+p.Length > size {
+			return nil, ErrImgFormat
+		}
+		parts = append(parts, p)
+	}
+	if len(parts) == 0 {
+		return nil, ErrImgFormat
+	}
+	return
+}
+
+// readgpt reads GUID partitions table.
+func readgpt(r io.ReaderAt, size int64) (parts []Partition, err error) {
+	var le = binary.LittleEndian
+	var hdr [imgSector]byte
+	if _, err = r.ReadAt(hdr[:], imgSector); err != nil {
+		return
+	}
+	if string(hdr[:8]) != "EFI PART" {
+		return nil, ErrImgFormat
+	}
+	var lba = int64(le.Uint64(hdr[72:]))
+	var num = int64(le.Uint32(hdr[80:]))
+	var esize = int64(le.Uint32(hdr[84:]))
+	if esize < 128 || esize > 4096 || num > 1024 {
+		return nil, ErrImgFormat
+	}
+	if lba < 2 || lba > size/imgSector || num*esize > size-lba*imgSector {
+		return nil, ErrImgFormat // table is outside of image
+	}
+	var table = make([]byte, num*esize)
+	if _, err = r.ReadAt(table, lba*imgSector); err != nil {
+		return
+	}
+	for i := int64(0); i < num; i++ {
+		var ent = table[i*esize:]
+		var used bool
+		for _, b := range ent[:16] { // type GUID
+			if b != 0 {
+				used = true
+				break
+			}
+		}
+		if !used {
+			continue
+		}
+		var first, last = int64(le.Uint64(ent[32:])), int64(le.Uint64(ent[40:]))
+		if first < 0 || last < first || last >= size/imgSector {
+			return nil, ErrImgFormat
+		}
+		var p = Partition{
+			Num:    int(i) + 1,
+			Offset: first * imgSector,
+			Length: (last - first + 1) * imgSector,
+		}
+		if p.Length <= 0 || p.Offset+p.Length > size {
+			return nil, ErrImgFormat
+		}
+		parts = append(parts, p)
+	}
+	return
+}
+
+// PartFS represents partitioned disk image as file system,
+// where each partition is the folder with name "p1", "p2", etc.
+// File systems of partitions are opened on first access.
+type PartFS struct {
+	r     io.ReaderAt
+	parts []Partition
+	fsys  []fs.FS // opened file systems of partitions
+	mux   sync.Mutex
+}
+
+// Partitions returns list of disk partitions.
+func (pfs *PartFS) Partitions() []Partition {
+	return pfs.parts
+}
+
+// sub returns partition index for given path, and remained path.
+func (pfs *PartFS) sub(fpath string) (int, string, error) {
+	var chunk, rest, _ = strings.Cut(fpath, "/")
+	if rest == "" {
+		rest = "."
+	}
+	if strings.HasPrefix(chunk, "p") {
+		if num, err := strconv.Atoi(chunk[1:]); err == nil {
+			for i, p := range pfs.parts {
+				if p.Num == num {
+					return i, rest, nil
+				}
+			}
+		}
+	}
+	return 0, "", &fs.PathError{Op: "open", Path: fpath, Err: fs.ErrNotExist}
+}
+
+// partfs returns opened file system for partition with given index.
+func (pfs *PartFS) partfs(i int) (fsys fs.FS, err error) {
+	pfs.mux.Lock()
+	defer pfs.mux.Unlock()
+	if pfs.fsys[i] == nil {
+		var p = pfs.parts[i]
+		if pfs.fsys[i], err = OpenDiskImage(io.NewSectionReader(pfs.r, p.Offset, p.Length), p.Length); err != nil {
+			return
+		}
+	}
+	return pfs.fsys[i], nil
+}
+
+// Stat implements fs.StatFS interface.
+func (pfs *PartFS) Stat(fpath string) (fs.FileInfo, error) {
+	if !fs.ValidPath(fpath) {
+		return nil, &fs.PathError{Op: "stat", Path: fpath, Err: fs.ErrInvalid}
+	}
+	if fpath == "." {
+		return PartFileInfo{name: "."}, nil
+	}
+	var i, rest, err = pfs.sub(fpath)
+	if err != nil {
+		return nil, err
+	}
+	if rest == "." {
+		return PartFileInfo{name: "p" + strconv.Itoa(pfs.parts[i].Num), size: pfs.parts[i].Length}, nil
+	}
+	var fsys fs.FS
+	if fsys, err = pfs.partfs(i); err != nil {
+		return nil, err
+	}
+	return fs.Stat(fsys, rest)
+}
+
+// ReadDir implements fs.ReadDirFS interface.
+func (pfs *PartFS) ReadDir(fpath string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(fpath) {
+		return nil, &fs.PathError{Op: "readdir", Path: fpath, Err: fs.ErrInvalid}
+	}
+	if fpath == "." {
+		var list = make([]fs.DirEntry, len(pfs.parts))
+		for i, p := range pfs.parts {
+			list[i] = PartFileInfo{name: "p" + strconv.Itoa(p.Num), size: p.Length}
+		}
+		return list, nil
+	}
+	var i, rest, err = pfs.sub(fpath)
+	if err != nil {
+		return nil, err
+	}
+	var fsys fs.FS
+	if fsys, err = pfs.partfs(i); err != nil {
+		return nil, err
+	}
+	return fs.ReadDir(fsys, rest)
+}
+
+// Open implements fs.FS interface.
+func (pfs *PartFS) Open(fpath string) (fs.File, error) {
+	if !fs.ValidPath(fpath) {
+		return nil, &fs.PathError{Op: "open", Path: fpath, Err: fs.ErrInvalid}
+	}
+	if fpath == "." {
+		var list, _ = pfs.ReadDir(".")
+		return &imgdir{fi: PartFileInfo{name: "."}, list: list}, nil
+	}
+	var i, rest, err = pfs.sub(fpath)
+	if err != nil {
+		return nil, err
+	}
+	var fsys fs.FS
+	if fsys, err = pfs.partfs(i); err != nil {
+		return nil, err
+	}
+	if rest == "." { // partition folder
+		var list []fs.DirEntry
+		if list, err = fs.ReadDir(fsys, "."); err != nil {
+			return nil, err
+		}
+		return &imgdir{fi: PartFileInfo{name: "p" + strconv.Itoa(pfs.parts[i].Num), size: pfs.parts[i].Length}, list: list}, nil
+	}
+	return fsys.Open(rest)
+}
+
+// PartFileInfo describes partition folder, and provides
+// fs.FileInfo and fs.DirEntry implementation.
+type PartFileInfo struct {
+	name string
+	size int64
+}
+
+// fs.FileInfo implementation.
+func (fi PartFileInfo) Name() string {
+	return fi.name
+}
+
+// fs.FileInfo implementation. Returns size of partition.
+func (fi PartFileInfo) Size() int64 {
+	return fi.size
+}
+
+// fs.FileInfo implementation.
+func (fi PartFileInfo) Mode() fs.FileMode {
+	return fs.ModeDir | 0555
+}
+
+// fs.FileInfo implementation.
+func (fi PartFileInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+// fs.FileInfo implementation.
+func (fi PartFileInfo) IsDir() bool {
+	return true
+}
+
+func (fi PartFileInfo) IsRealDir() bool {
+	return true
+}
+
+func (fi PartFileInfo) Type() fs.FileMode {
+	return fs.ModeDir
+}
+
+// Info provided for fs.DirEntry compatibility and returns object itself.
+func (fi PartFileInfo) Info() (fs.FileInfo, error) {
+	return fi, nil
+}
+
+// fs.FileInfo implementation. Returns structure itself.
+func (fi PartFileInfo) Sys() interface{} {
+	return fi
+}
+
+func (fi PartFileInfo) String() string {
+	return fs.FormatDirEntry(fi)
+}
+
+// ImgJoint opens raw disk image with FAT12/16/32 or ext2/3/4 file system,
+// or partitioned disk image with MBR or GPT partitions table, where
+// partitions are presented as "p1", "p2", etc folders.
+// Key is external path to disk image file.
+type ImgJoint struct {
+	Base Joint
+	FSJoint
+}
+
+func (j *ImgJoint) Make(base Joint, imgpath string) (err error) {
+	var size int64
+	if j.Base, size, err = openbase(base, imgpath); err != nil {
+		return
+	}
+	if j.FS, err = OpenDiskImage(j.Base, size); err != nil {
+		return
+	}
+	return
+}
+
+func (j *ImgJoint) Cleanup() (err error) {
+	j.FSJoint.Cleanup()
+	if j.Base != nil {
+		err = j.Base.Cleanup()
+		j.Base = nil
+	}
+	return err
+}
+
+func (j *ImgJoint) Stat() (fs.FileInfo, error) {
+	if j.path == "." { // base disk image
+		return statbase(j.Base)
+	}
+	return j.FSJoint.Stat()
+}
@@ -0,0 +1,191 @@
+package joint_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// Disk image "testdata/disk.img" have MBR partitions table with
+// FAT16 file system at 1st partition, ext4 at 2nd, and ext2 at 3rd.
+const diskimg = "testdata/disk.img"
+
+// Precalculated CRC32 codes of files present only at FAT partition
+// of disk image, they are added to filecrc.
+var imgcrc = map[string]uint32{
+	"IMG_0001.TXT":       0x519025e9, // copy of "fox.txt"
+	"Long File Name.txt": 0x98b2c5bd, // copy of "doc1.txt"
+}
+
+func init() {
+	for name, crc := range imgcrc {
+		filecrc[name] = crc
+	}
+}
+
+// Files list in disk image.
+var imgfiles = []string{
+	"p1/fox.txt",
+	"p1/data/lorem1.txt",
+	"p1/data/рыба.txt",
+	"p1/data/docs/doc1.txt",
+	"p1/data/доки/док2.txt",
+	"p1/DCIM/Long File Name.txt",
+	"p1/DCIM/100CANON/IMG_0001.TXT",
+	"p2/fox.txt",
+	"p2/data/lorem1.txt",
+	"p2/data/рыба.txt",
+	"p2/data/docs/doc1.txt",
+	"p2/data/доки/док2.txt",
+	"p3/fox.txt",
+}
+
+// Directories list in disk image.
+var imgdirs = map[string][]string{
+	"":                 {"p1", "p2", "p3"},
+	"p1":               {"fox.txt", "data", "DCIM"},
+	"p1/data":          {"lorem1.txt", "рыба.txt", "docs", "доки"},
+	"p1/data/docs":     {"doc1.txt"},
+	"p1/data/доки":     {"док2.txt"},
+	"p1/DCIM":          {"Long File Name.txt", "100CANON"},
+	"p1/DCIM/100CANON": {"IMG_0001.TXT"},
+	"p2/data":          {"lorem1.txt", "рыба.txt", "docs", "доки"},
+	"p3/disk":          {"internal.iso"},
+}
+
+// Check file reading in disk image placed at primary filesystem.
+func TestImgReadFile(t *testing.T) {
+	var err error
+
+	var j jnt.Joint = &jnt.ImgJoint{}
+	if err = j.Make(nil, diskimg); err != nil {
+		t.Fatal(err)
+	}
+	defer j.Cleanup()
+
+	for _, fpath := range imgfiles {
+		if err = checkFile(j, fpath); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Check directory list in disk image placed at primary filesystem.
+func TestImgDirList(t *testing.T) {
+	var err error
+
+	var j jnt.Joint = &jnt.ImgJoint{}
+	if err = j.Make(nil, diskimg); err != nil {
+		t.Fatal(err)
+	}
+	defer j.Cleanup()
+
+	for fpath := range imgdirs {
+		if err = checkDir(j, fpath, imgdirs); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Check up case-insensitive names lookup at FAT file system.
+func TestImgFatCase(t *testing.T) {
+	var err error
+
+	var j = &jnt.ImgJoint{}
+	if err = j.Make(nil, diskimg); err != nil {
+		t.Fatal(err)
+	}
+	defer j.Cleanup()
+
+	var fi fs.FileInfo
+	if fi, err = j.Info("p1/dcim/LONG FILE NAME.TXT"); err != nil {
+		t.Fatal(err)
+	}
+	if fi.Name() != "Long File Name.txt" {
+		t.Fatal("file name should be returned as it stored at file system")
+	}
+}
+
+// Check ISO-disk reading placed at ext2 partition with
+// indirect blocks mapping.
+func TestImgIsoReadFile(t *testing.T) {
+	var err error
+
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var f fs.File
+	if f, err = jp.Open(diskimg + "/p3/disk/internal.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var b []byte
+	if b, err = io.ReadAll(f); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "The quick brown fox jumps over the lazy dog." {
+		t.Fatal("read string does not match to pattern")
+	}
+}
+
+func TestImgPool(t *testing.T) {
+	var err error
+
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var sp fs.FS
+	if sp, err = jp.Sub(diskimg); err != nil {
+		t.Fatal(err)
+	}
+
+	// test FS at the end
+	if err = fstest.TestFS(sp, imgfiles...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// gptimage returns disk image with protective MBR and GPT header
+// with given number and size of entries, and one partition entry.
+func gptimage(num, esize uint32, first, last uint64) []byte {
+	var le = binary.LittleEndian
+	var img = make([]byte, 64*1024)
+	img[446+4] = 0xee // protective MBR
+	img[510], img[511] = 0x55, 0xaa
+	copy(img[512:], "EFI PART")
+	le.PutUint64(img[512+72:], 2) // table LBA
+	le.PutUint32(img[512+80:], num)
+	le.PutUint32(img[512+84:], esize)
+	img[1024] = 1 // type GUID of used entry
+	le.PutUint64(img[1024+32:], first)
+	le.PutUint64(img[1024+40:], last)
+	return img
+}
+
+func TestImgGptBounds(t *testing.T) {
+	var img = gptimage(4, 128, 34, 100)
+	var parts, err = jnt.ReadPartitions(bytes.NewReader(img), int64(len(img)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 1 || parts[0].Offset != 34*512 || parts[0].Length != 67*512 {
+		t.Fatalf("partitions does not match: %v", parts)
+	}
+	for name, img := range map[string][]byte{
+		"huge entry":     gptimage(1024, 0xffffffff, 34, 100),
+		"huge table":     gptimage(1024, 4096, 34, 100),
+		"huge partition": gptimage(4, 128, 34, 1<<63),
+		"inverted":       gptimage(4, 128, 100, 34),
+	} {
+		if _, err = jnt.ReadPartitions(bytes.NewReader(img), int64(len(img))); !errors.Is(err, jnt.ErrImgFormat) {
+			t.Fatalf("%s: image should not be recognized, got %v", name, err)
+		}
+	}
+}
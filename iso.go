@@ -1,6 +1,8 @@
 package joint
 
 import (
+	"encoding/binary"
+	"errors"
 	"io"
 	"io/fs"
 	"strings"
@@ -9,6 +11,10 @@ import (
 	"golang.org/x/text/encoding/charmap"
 )
 
+var (
+	ErrIsoRecord = errors.New("invalid ISO9660 directory record")
+)
+
 // IsoJoint opens file with ISO9660 disk and prepares disk-structure
 // to access to nested files.
 // Key is external path, to ISO9660-file disk image at local filesystem.
@@ -155,7 +161,7 @@ func (j *IsoJoint) ReadDir(n int) (list []fs.DirEntry, err error) {
 
 func (j *IsoJoint) Stat() (fs.FileInfo, error) {
 	if j.File.IsDir() && j.SectionReader != nil { // base ISO-disk
-		return j.Base.Stat()
+		return statbase(j.Base)
 	}
 	return IsoFileInfo{j.File}, nil
 }
@@ -181,14 +187,14 @@ func (fi IsoFileInfo) Name() string {
 
 func (fi IsoFileInfo) Mode() fs.FileMode {
 	var mode = fi.File.Mode()
-	if mode.IsRegular() && IsTypeIso(fi.File.Name()) {
+	if mode.IsRegular() && IsTypeContainer(fi.File.Name()) {
 		mode |= fs.ModeDir
 	}
 	return mode
 }
 
 func (fi IsoFileInfo) IsDir() bool {
-	return fi.File.IsDir() || IsTypeIso(fi.File.Name())
+	return fi.File.IsDir() || IsTypeContainer(fi.File.Name())
 }
 
 func (fi IsoFileInfo) IsRealDir() bool {
@@ -212,3 +218,198 @@ func (fi IsoFileInfo) Sys() interface{} {
 func (fi IsoFileInfo) String() string {
 	return fs.FormatDirEntry(fi)
 }
+
+// Lstat returns file info without following of symbolic link.
+// Files at ISO-image are never followed, so it's the same as Info.
+func (j *IsoJoint) Lstat(fpath string) (fs.FileInfo, error) {
+	if fpath == "." {
+		fpath = ""
+	}
+	return j.Info(fpath)
+}
+
+// ReadLink returns target of symbolic link stored
+// at Rock Ridge SL-entries of ISO-image.
+func (j *IsoJoint) ReadLink(fpath string) (string, error) {
+	var fi, err = j.Lstat(fpath)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: fpath, Err: err}
+	}
+	if fi.Mode()&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: fpath, Err: fs.ErrInvalid}
+	}
+	var ents [][]byte
+	if ents, err = isoLookup(j.Base, fpath); err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: fpath, Err: err}
+	}
+	return isoLinkTarget(ents), nil
+}
+
+// isoLookup finds directory record of file at given path,
+// and returns its System Use entries.
+func isoLookup(r io.ReaderAt, fpath string) (ents [][]byte, err error) {
+	var sec = make([]byte, 2048)
+	var root []byte
+	for i := int64(16); root == nil; i++ { // find primary volume descriptor
+		if _, err = r.ReadAt(sec, i*2048); err != nil {
+			return
+		}
+		if string(sec[1:6]) != "CD001" || sec[0] == 255 {
+			return nil, fs.ErrNotExist
+		}
+		if sec[0] == 1 {
+			root = append([]byte{}, sec[156:190]...)
+		}
+	}
+
+	var recs [][]byte
+	if recs, err = isoDirRecords(r, root); err != nil {
+		return
+	}
+	var skip = -1 // SUSP is absent
+	if len(recs) > 0 {
+		var sp [][]byte
+		if sp, err = isoSystemUse(r, recs[0], 0); err != nil {
+			return
+		}
+		for _, e := range sp {
+			if string(e[:2]) == "SP" && len(e) >= 7 && e[4] == 0xbe && e[5] == 0xef {
+				skip = int(e[6])
+			}
+		}
+	}
+	if skip < 0 {
+		return nil, fs.ErrInvalid
+	}
+
+	var dec = charmap.Windows1251.NewDecoder()
+	var chunks = strings.Split(fpath, "/")
+	for i, chunk := range chunks {
+		var found bool
+		for _, rec := range recs {
+			if 33+int(rec[32]) > len(rec) {
+				return nil, ErrIsoRecord
+			}
+			var ident = rec[33 : 33+rec[32]]
+			if len(ident) == 1 && ident[0] <= 1 { // "." and ".." records
+				continue
+			}
+			if ents, err = isoSystemUse(r, rec, skip); err != nil {
+				return
+			}
+			var name string
+			for _, e := range ents {
+				if string(e[:2]) == "NM" && len(e) > 4 {
+					name += string(e[5:])
+				}
+			}
+			if name == "" {
+				name = string(ident)
+				if rec[25]&2 == 0 { // not directory
+					name, _, _ = strings.Cut(name, ";")
+					name = strings.TrimSuffix(name, ".")
+				}
+			}
+			if name, _ = dec.String(name); name == chunk {
+				found = true
+				if i < len(chunks)-1 {
+					if recs, err = isoDirRecords(r, rec); err != nil {
+						return
+					}
+				}
+				break
+			}
+		}
+		if !found {
+			return nil, fs.ErrNotExist
+		}
+	}
+	return
+}
+
+// isoDirRecords reads all directory records of directory
+// described by given record.
+func isoDirRecords(r io.ReaderAt, dir []byte) (recs [][]byte, err error) {
+	var le = binary.LittleEndian
+	var loc, size = int64(le.Uint32(dir[2:])), int64(le.Uint32(dir[10:]))
+	for pos := int64(0); pos < size; pos += 2048 {
+		var sec = make([]byte, 2048)
+		if _, err = r.ReadAt(sec, loc*2048+pos); err != nil {
+			return
+		}
+		for i := 0; i < len(sec) && sec[i] >= 34 && i+int(sec[i]) <= len(sec); i += int(sec[i]) {
+			recs = append(recs, sec[i:i+int(sec[i])])
+		}
+	}
+	return
+}
+
+// isoSystemUse returns System Use entries of directory record,
+// including entries placed at continuation areas.
+func isoSystemUse(r io.ReaderAt, rec []byte, skip int) (ents [][]byte, err error) {
+	var le = binary.LittleEndian
+	var n = int(rec[32])
+	var pos = 33 + n + (n+1)%2
+	if pos > len(rec) {
+		return nil, ErrIsoRecord
+	}
+	var data = rec[pos:]
+	if skip > len(data) {
+		return nil, ErrIsoRecord
+	}
+	data = data[skip:]
+	var ce = 0
+	for len(data) >= 4 && int(data[2]) >= 4 && int(data[2]) <= len(data) {
+		var e = data[:data[2]]
+		data = data[data[2]:]
+		if string(e[:2]) == "CE" && len(e) >= 28 && ce < 16 { // continuation area
+			ce++
+			var size = le.Uint32(e[20:])
+			if size > 2048 { // continuation area is placed at one sector
+				return nil, ErrIsoRecord
+			}
+			var ca = make([]byte, size)
+			if _, err = r.ReadAt(ca, int64(le.Uint32(e[4:]))*2048+int64(le.Uint32(e[12:]))); err != nil {
+				return
+			}
+			data = ca
+			continue
+		}
+		ents = append(ents, e)
+	}
+	return
+}
+
+// isoLinkTarget composes symbolic link target from SL-entries.
+func isoLinkTarget(ents [][]byte) string {
+	var parts []string
+	var root, join bool
+	for _, e := range ents {
+		if string(e[:2]) != "SL" || len(e) < 5 {
+			continue
+		}
+		for comp := e[5:]; len(comp) >= 2 && 2+int(comp[1]) <= len(comp); comp = comp[2+comp[1]:] {
+			var flags, part = comp[0], string(comp[2 : 2+comp[1]])
+			switch {
+			case flags&0x08 != 0:
+				root = true
+				continue
+			case flags&0x02 != 0:
+				part = "."
+			case flags&0x04 != 0:
+				part = ".."
+			}
+			if join && len(parts) > 0 {
+				parts[len(parts)-1] += part
+			} else {
+				parts = append(parts, part)
+			}
+			join = flags&0x01 != 0
+		}
+	}
+	var target = strings.Join(parts, "/")
+	if root {
+		target = "/" + target
+	}
+	return target
+}
@@ -1,9 +1,11 @@
 package joint
 
 import (
+	"context"
 	"errors"
 	"io"
 	"io/fs"
+	"log/slog"
 	"path"
 	"sort"
 	"strings"
@@ -11,6 +13,13 @@ import (
 	"time"
 )
 
+var (
+	ErrRealDir    = errors.New("path with container extension points to real directory")
+	ErrLinkLoop   = errors.New("too many levels of symbolic links")
+	ErrLinkDenied = errors.New("symbolic link is denied by links policy")
+	ErrReadOnly   = errors.New("file system does not support writing")
+)
+
 // RFile combines fs.File interface and io.Seeker interface.
 type RFile interface {
 	io.Reader
@@ -39,31 +48,133 @@ type Joint interface {
 	RFile
 }
 
+// LinkJoint is joint to file system with symbolic links. Lstat and
+// ReadLink receives local path at the joint in the same way as Open,
+// and they can be called without opened file. It matches fs.ReadLinkFS.
+type LinkJoint interface {
+	Joint
+	Lstat(string) (fs.FileInfo, error) // returns file info without following of link
+	ReadLink(string) (string, error)   // returns target of symbolic link
+}
+
+// WriteJoint is joint to file system that can be modified. Put, Mkdir
+// and Remove receives local path at the joint in the same way as Open,
+// and they should be called without opened file.
+type WriteJoint interface {
+	Joint
+	Put(string, io.Reader) error // creates or truncates file, and writes content of reader
+	Mkdir(string) error          // creates directory, parent directory should exist
+	Remove(string) error         // removes file or empty directory
+}
+
+// ResumeJoint is joint to file system where writing of file
+// can be continued from given offset.
+type ResumeJoint interface {
+	WriteJoint
+	PutFrom(string, io.Reader, int64) error // writes content of reader to file from given offset
+}
+
+// RenameJoint is joint to file system where files can be moved
+// to another place at the same joint.
+type RenameJoint interface {
+	Joint
+	Rename(oldpath, newpath string) error // moves file or directory, existing file is replaced
+}
+
+// ChtimesJoint is joint to file system where modification time
+// of files can be changed.
+type ChtimesJoint interface {
+	Joint
+	Chtimes(string, time.Time) error // changes modification time of file
+}
+
+// CopyJoint is joint that can copy files at server side
+// without transferring content through the client.
+type CopyJoint interface {
+	Joint
+	CopyFile(src, dst string) error // copies file to new place at the same joint
+}
+
+// LinkPolicy determines how JointPool follows symbolic links.
+type LinkPolicy int
+
+const (
+	// LinkNative leaves links as is for joints backend. Local file
+	// system and SFTP follow links, FTP and ISO-images does not.
+	LinkNative LinkPolicy = iota
+	// LinkFollow resolves links by ReadLink for any joints, if backend
+	// returns link itself or can not find path with link inside.
+	LinkFollow
+	// LinkDeny disables opening of files pointed by symbolic links.
+	LinkDeny
+)
+
+// ProbeRealDir opens given path at the joint, and checks up
+// that it points to real directory.
+func ProbeRealDir(j Joint, fpath string) (isdir bool, err error) {
+	if _, err = j.Open(fpath); err != nil {
+		return
+	}
+	defer j.Close()
+	var fi fs.FileInfo
+	if fi, err = j.Stat(); err != nil {
+		return
+	}
+	if jfi, ok := fi.(FileInfo); ok {
+		return jfi.IsRealDir(), nil
+	}
+	return fi.IsDir(), nil
+}
+
 // MakeJoint creates joint with all subsequent chain of joints.
-// Please note that folders with .iso extension and non ISO-images
-// with .iso extension will cause an error.
+// Each path with extension of registered container is opened by
+// joint of container format, and if it fails, path is probed by
+// previous joint in chain, and real directories are passed as is.
+// If given path ends with real directory, ErrRealDir is returned.
+// Files recognized as containers by content are opened by joint
+// of recognized format.
+// Please note that files with extension of registered container
+// of other format will cause an error.
 func MakeJoint(fullpath string) (j Joint, err error) {
-	var addr, fpath, is = SplitUrl(fullpath)
-	if HasFoldPrefix(fullpath, "ftp://") {
-		j = &FtpJoint{}
-		if err = j.Make(nil, addr); err != nil {
-			return
+	return makejoint(context.Background(), notrace, nil, nil, nil, fullpath)
+}
+
+// makejoint is MakeJoint that traces making of each joint in chain
+// by given tracer as nested spans of given context, and recognizes
+// containers by content with results stored at given cache.
+// Calls of containers to their bases are traced within given
+// context of chain. Stages of network joint are logged by given makelog.
+func makejoint(ctx context.Context, tr Tracer, tc *tracectx, ml *makelog, sc *sniffcache, fullpath string) (j Joint, err error) {
+	var ctx1, span = tr.Start(ctx, "MakeJoint", slog.String("path", Redact(fullpath)))
+	defer func() {
+		if err == ErrRealDir { // real directory is not a failure
+			span.SetAttrs(slog.Bool("realdir", true))
+			span.End(nil)
+		} else {
+			span.End(err)
 		}
+	}()
+
+	var addr, fpath, is = SplitUrl(fullpath)
+	var mkaddr string // address to make network joint
+	if fsys, ok := GetFS(addr); ok {
+		j = &FSJoint{FS: fsys}
+	} else if HasFoldPrefix(fullpath, "ftp://") {
+		j, mkaddr = &FtpJoint{}, addr
 	} else if HasFoldPrefix(fullpath, "sftp://") {
-		j = &SftpJoint{}
-		if err = j.Make(nil, addr); err != nil {
-			return
-		}
+		j, mkaddr = &SftpJoint{}, addr
+	} else if HasFoldPrefix(fullpath, "smb://") {
+		var share string
+		share, fpath, _ = strings.Cut(fpath, "/")
+		j, mkaddr = &SmbJoint{}, addr+"/"+share
+	} else if HasFoldPrefix(fullpath, "s3://") || HasFoldPrefix(fullpath, "s3+http://") {
+		j, mkaddr = &S3Joint{}, addr
 	} else if HasFoldPrefix(fullpath, "http://") || HasFoldPrefix(fullpath, "https://") {
-		var root, ok = FindDavRoot(addr, fpath)
-		if !ok {
-			err = fs.ErrNotExist
-			return
-		}
-		fpath = fpath[len(root)-1:]
-		j = &DavJoint{}
-		if err = j.Make(nil, addr+root); err != nil {
-			return
+		if root, ok := FindDavRoot(addr, fpath); ok {
+			fpath = fpath[len(root)-1:]
+			j, mkaddr = &DavJoint{}, addr+root
+		} else { // plain HTTP-server without WebDAV support
+			j, mkaddr = &HttpJoint{}, addr
 		}
 	} else if !is {
 		j = &SysJoint{dir: addr}
@@ -71,36 +182,47 @@ func MakeJoint(fullpath string) (j Joint, err error) {
 		err = fs.ErrNotExist
 		return
 	}
-
-	var jpos = 0
-	for {
-		var p1 = strings.Index(fpath[jpos:], ".iso/")
-		var p2 = strings.Index(fpath[jpos:], ".ISO/")
-		if p1 == p2 { // p1 == -1 && p2 == -1
-			break
-		}
-		var p int
-		if p1 == -1 {
-			p = p2
-		} else if p2 == -1 {
-			p = p1
-		} else {
-			p = min(p1, p2)
-		}
-		var key = fpath[:p+4]
-		var jiso = &IsoJoint{}
-		if err = jiso.Make(j, key); err != nil {
+	if mkaddr != "" {
+		if err = tracemake(ctx1, tr, ml, j, nil, mkaddr); err != nil {
 			return
 		}
-		j, jpos = jiso, p+5
 	}
-	if IsTypeIso(fpath[jpos:]) {
-		var key = fpath[jpos:]
-		var jiso = &IsoJoint{}
-		if err = jiso.Make(j, key); err != nil {
-			return
+
+	var jpos = 0 // start of local path for current joint
+	for p := 0; p <= len(fpath); p++ {
+		if p < len(fpath) && fpath[p] != '/' {
+			continue
+		}
+		var key = fpath[jpos:p]
+		var maker = ContainerMaker(key)
+		if maker == nil {
+			maker = sc.maker(fullpath[:len(fullpath)-len(fpath)+p])
+		}
+		if maker != nil {
+			var base = j
+			if tc != nil {
+				base = &tracejoint{Joint: j, tr: tr, tc: tc}
+			}
+			var jc = maker()
+			if err = tracemake(ctx1, tr, nil, jc, base, key); err != nil {
+				// container can not be opened,
+				// so check up that it's real directory
+				if base.Busy() {
+					base.Close()
+				}
+				if isdir, err1 := ProbeRealDir(base, key); err1 != nil || !isdir {
+					j.Cleanup()
+					return nil, err
+				}
+				if p == len(fpath) {
+					j.Cleanup()
+					return nil, ErrRealDir
+				}
+				err = nil
+				continue
+			}
+			j, jpos = jc, p+1
 		}
-		j = jiso
 	}
 	return
 }
@@ -134,17 +256,20 @@ func (fi fileinfo) Name() (name string) {
 
 func (fi fileinfo) Mode() fs.FileMode {
 	var mode = fi.FileInfo.Mode()
-	if mode.IsRegular() && IsTypeIso(fi.Name()) {
+	if mode.IsRegular() && IsTypeContainer(fi.Name()) {
 		mode |= fs.ModeDir
 	}
 	return mode
 }
 
 func (fi fileinfo) IsDir() bool {
-	return fi.FileInfo.IsDir() || IsTypeIso(fi.Name())
+	return fi.FileInfo.IsDir() || IsTypeContainer(fi.Name())
 }
 
 func (fi fileinfo) IsRealDir() bool {
+	if jfi, ok := fi.FileInfo.(FileInfo); ok { // already have derived IsDir
+		return jfi.IsRealDir()
+	}
 	return fi.FileInfo.IsDir()
 }
 
@@ -161,6 +286,47 @@ func (fi fileinfo) String() string {
 	return fs.FormatDirEntry(fi)
 }
 
+// continfo is wrapper around fs.FileInfo of file that was
+// recognized as container by its content.
+type continfo struct {
+	fs.FileInfo
+}
+
+func (fi continfo) Mode() fs.FileMode {
+	return fi.FileInfo.Mode() | fs.ModeDir
+}
+
+func (fi continfo) IsDir() bool {
+	return true
+}
+
+func (fi continfo) IsRealDir() bool {
+	return false
+}
+
+func (fi continfo) Type() fs.FileMode {
+	return fs.ModeDir
+}
+
+// Info provided for fs.DirEntry compatibility and returns object itself.
+func (fi continfo) Info() (fs.FileInfo, error) {
+	return fi, nil
+}
+
+func (fi continfo) String() string {
+	return fs.FormatDirEntry(fi)
+}
+
+// statbase returns file info of container file opened at base joint.
+// Container is always represented as directory regardless of its name.
+func statbase(base Joint) (fs.FileInfo, error) {
+	var fi, err = base.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return continfo{fi}, nil
+}
+
 // ToFileInfo converts base fs.FileInfo to FileInfo that compatible both
 // with fs.FileInfo and with fs.DirEntry interface and have derived IsDir.
 func ToFileInfo(fi fs.FileInfo) FileInfo {
@@ -183,6 +349,7 @@ func ToDirEntry(fi fs.FileInfo) fs.DirEntry {
 // It has pointer to JointCache that it binded to.
 type JointWrap struct {
 	jc *JointCache
+	tc *tracectx // context of calls to traced chain
 	Joint
 }
 
@@ -194,23 +361,77 @@ func (jw JointWrap) GetCache() *JointCache {
 // Close calls inherited Close-function and puts joint into binded cache.
 func (jw JointWrap) Close() error {
 	var err = jw.Joint.Close()
+	if jw.tc != nil {
+		jw.tc.ctx = nil
+	}
 	if jw.jc != nil {
 		jw.jc.Put(jw)
 	}
 	return err
 }
 
+// Read calls inherited Read-function and counts read data at binded cache.
+func (jw JointWrap) Read(b []byte) (n int, err error) {
+	if jw.jc == nil {
+		return jw.Joint.Read(b)
+	}
+	var ctx1, span = jw.tc.start(jw.jc.tr, "Joint.Read", slog.Int("len", len(b)))
+	defer func() { span.End(spanerr(err)) }()
+	defer jw.tc.swap(jw.tc.swap(ctx1))
+	var t0 = time.Now()
+	n, err = jw.Joint.Read(b)
+	jw.jc.onread(n, time.Since(t0))
+	return
+}
+
+// ReadAt calls inherited ReadAt-function and counts read data at binded cache.
+func (jw JointWrap) ReadAt(b []byte, off int64) (n int, err error) {
+	if jw.jc == nil {
+		return jw.Joint.ReadAt(b, off)
+	}
+	var ctx1, span = jw.tc.start(jw.jc.tr, "Joint.ReadAt", slog.Int("len", len(b)), slog.Int64("off", off))
+	defer func() { span.End(spanerr(err)) }()
+	defer jw.tc.swap(jw.tc.swap(ctx1))
+	var t0 = time.Now()
+	n, err = jw.Joint.ReadAt(b, off)
+	jw.jc.onread(n, time.Since(t0))
+	return
+}
+
+// drop cleans up joint that was failed with given error
+// instead of putting it back into binded cache.
+func (jw JointWrap) drop(err error) {
+	var err1 = jw.Joint.Cleanup()
+	if jw.jc != nil {
+		jw.jc.untrace(jw.Joint)
+		jw.jc.onerror(err, err1)
+	}
+}
+
 type Config struct {
-	// Timeout to establish connection to FTP-server.
+	// Timeout to establish connection to network services.
 	DialTimeout time.Duration `json:"dial-timeout" yaml:"dial-timeout" xml:"dial-timeout"`
 	// Expiration duration to keep opened iso-disk structures in cache from last access to it.
 	DiskCacheExpire time.Duration `json:"disk-cache-expire" yaml:"disk-cache-expire" xml:"disk-cache-expire"`
+	// Recognize containers by content of files without registered extension.
+	SniffContent bool `json:"sniff-content" yaml:"sniff-content" xml:"sniff-content"`
+	// Maximum number of content recognition results kept by each JointPool.
+	SniffCacheSize int `json:"sniff-cache-size" yaml:"sniff-cache-size" xml:"sniff-cache-size"`
+	// Policy of symbolic links following by JointPool.
+	LinkPolicy LinkPolicy `json:"link-policy" yaml:"link-policy" xml:"link-policy"`
+	// Maximum number of symbolic links resolved for one path.
+	MaxLinkHops int `json:"max-link-hops" yaml:"max-link-hops" xml:"max-link-hops"`
+	// Expiration duration of remembered path where WebDAV root was not found.
+	DavProbeExpire time.Duration `json:"dav-probe-expire" yaml:"dav-probe-expire" xml:"dav-probe-expire"`
 }
 
 // Cfg is singleton with timeouts settings for all joints.
 var Cfg = Config{
 	DialTimeout:     5 * time.Second,
 	DiskCacheExpire: 2 * time.Minute,
+	SniffCacheSize:  4096,
+	MaxLinkHops:     40,
+	DavProbeExpire:  5 * time.Minute,
 }
 
 // JointCache implements cache with opened joints to some file system resource.
@@ -219,11 +440,21 @@ type JointCache struct {
 	cache  []Joint
 	expire []*time.Timer
 	mux    sync.Mutex
+	faults *Faults             // failures injected into new joints
+	obs    Observer            // receiver of cache events
+	stats  jointstats          // counters of cache events
+	log    *slog.Logger        // logger of cache events
+	tr     Tracer              // tracer of cache operations
+	traced map[Joint]*tracectx // contexts of traced chains of joints
+
+	sniffed *sniffcache // results of content recognition of pool
 }
 
 func NewJointCache(key string) *JointCache {
 	return &JointCache{
 		key: key,
+		log: nolog,
+		tr:  notrace,
 	}
 }
 
@@ -238,15 +469,26 @@ func (jc *JointCache) Key() string {
 // be provided another JointCache to work with their file system.
 // Use JointPool on this case.
 func (jc *JointCache) Open(fpath string) (f fs.File, err error) {
+	return jc.open(context.Background(), fpath)
+}
+
+// open is Open that traces getting of joint and opening of file
+// as nested spans of given context.
+func (jc *JointCache) open(ctx context.Context, fpath string) (f fs.File, err error) {
 	var jw JointWrap
-	if jw, err = jc.Get(); err != nil {
+	if jw, err = jc.GetContext(ctx); err != nil {
 		return
 	}
-	if _, err = jw.Open(fpath); err != nil {
+	var ctx1, span = jc.tr.Start(ctx, "Joint.Open", slog.String("path", fpath))
+	var prev = jw.tc.swap(ctx1)
+	_, err = jw.Open(fpath)
+	jw.tc.swap(prev)
+	span.End(err)
+	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
 			jc.Put(jw) // reuse joint
 		} else if !errors.Is(err, fs.ErrExist) { // not already opened
-			jw.Cleanup() // drop the joint
+			jw.drop(err)
 		}
 		return
 	}
@@ -300,6 +542,7 @@ func (jc *JointCache) Close() (err error) {
 		errs[i] = j.Cleanup()
 	}
 	jc.cache = nil
+	jc.traced = nil
 	return errors.Join(errs...)
 }
 
@@ -341,12 +584,48 @@ func (jc *JointCache) Pop() (jw JointWrap, ok bool) {
 
 // Get retrieves cached disk joint, or makes new one.
 func (jc *JointCache) Get() (jw JointWrap, err error) {
-	jw, ok := jc.Pop()
-	if !ok {
-		if jw.Joint, err = MakeJoint(jc.key); err != nil {
-			return
+	return jc.GetContext(context.Background())
+}
+
+// GetContext is Get that traces taking of joint from the cache,
+// or making of new one, as nested span of given context.
+func (jc *JointCache) GetContext(ctx context.Context) (jw JointWrap, err error) {
+	var ctx1, span = jc.tr.Start(ctx, "JointCache.Get", slog.String("key", Redact(jc.key)))
+	defer func() {
+		if errors.Is(err, ErrRealDir) {
+			span.End(nil)
+		} else {
+			span.End(err)
 		}
-		jw.jc = jc // ensure that jc is owned while jw is outside of cache
+	}()
+
+	jw, ok := jc.Pop()
+	span.SetAttrs(slog.Bool("reuse", ok))
+	if ok {
+		jc.settrace(&jw, nil, ctx)
+		jc.onreuse()
+		return
+	}
+	var tc *tracectx // context of calls to traced chain
+	if jc.tr != notrace {
+		tc = &tracectx{}
+	}
+	var ml = &makelog{log: jc.log}
+	var t0 = time.Now()
+	if jc.faults != nil {
+		jw.Joint, err = jc.faults.makejoint(ctx1, jc.tr, tc, ml, jc.sniffed, jc.key)
+	} else {
+		jw.Joint, err = makejoint(ctx1, jc.tr, tc, ml, jc.sniffed, jc.key)
+	}
+	if !errors.Is(err, ErrRealDir) { // real directory is not a resource
+		jc.ondial(time.Since(t0), err, ml.failed)
+	}
+	if err != nil {
+		return
+	}
+	jw.jc = jc // ensure that jc is owned while jw is outside of cache
+	if tc != nil {
+		jc.settrace(&jw, tc, ctx)
 	}
 	return
 }
@@ -356,22 +635,31 @@ func (jc *JointCache) Put(j Joint) {
 	if jw, ok := j.(JointWrap); ok {
 		j = jw.Joint // strip wrapper to avoid overlapping
 	}
+	if jc.put(j) {
+		jc.onput()
+	}
+}
 
+// put appends joint to cache, and returns false
+// if it's already present.
+func (jc *JointCache) put(j Joint) bool {
 	jc.mux.Lock()
 	defer jc.mux.Unlock()
 
 	for _, f := range jc.cache { // ensure that joint does not present
 		if f == j {
-			return
+			return false
 		}
 	}
 
 	jc.cache = append(jc.cache, j)
 	jc.expire = append(jc.expire, time.AfterFunc(Cfg.DiskCacheExpire, func() {
 		if jw, ok := jc.Pop(); ok {
-			jw.Joint.Cleanup()
+			jc.untrace(jw.Joint)
+			jc.onexpire(jw.Joint.Cleanup())
 		}
 	}))
+	return true
 }
 
 // Eject joint from the cache.
@@ -388,6 +676,7 @@ func (jc *JointCache) Eject(j Joint) bool {
 			jc.expire[i].Stop()
 			jc.expire = append(jc.expire[:i], jc.expire[i+1:]...)
 			jc.cache = append(jc.cache[:i], jc.cache[i+1:]...)
+			delete(jc.traced, j)
 			return true
 		}
 	}
@@ -0,0 +1,334 @@
+// Package jointtest implements support for testing implementations
+// of Joint interface, in the same way as testing/fstest does it for
+// file systems.
+package jointtest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// Factory returns new joint made at tested file system.
+// Joint is cleaned up by test.
+type Factory func() (jnt.Joint, error)
+
+// chunk is size of data read to compare files content.
+const chunk = 64
+
+// TestJoint tests implementation of Joint interface. Factory is called
+// for each check to make new joint, dir is local path of directory at
+// the joint that should not be empty, and first regular file of it is
+// used for reading checks. ISO-images found at this directory should be
+// represented as virtual folders, and each of them is tested by nested
+// IsoJoint with the same checks at its root.
+//
+// Checked contract of joint:
+//   - joint is busy after Open, and is not busy after Close;
+//   - Open of busy joint returns fs.ErrExist error;
+//   - ReadDir(n) with n > 0 returns directory content by pages,
+//     and returns io.EOF with the last page, or with empty list;
+//   - Close resets reading position, so file opened again
+//     is read from the beginning;
+//   - Size, ReadAt and Seek are consistent with Read;
+//   - Cleanup can be called twice.
+func TestJoint(t *testing.T, factory Factory, dir string) {
+	t.Helper()
+	var list = readdir(t, factory, dir)
+	if len(list) == 0 {
+		t.Fatalf("directory '%s' is empty", dir)
+	}
+	var file, iso []string
+	for _, de := range list {
+		var fpath = path.Join(dir, de.Name())
+		if isiso(de.Name()) {
+			iso = append(iso, fpath)
+		} else if !de.IsDir() {
+			file = append(file, fpath)
+		}
+	}
+
+	t.Run("Busy", func(t *testing.T) { testBusy(t, factory, dir) })
+	t.Run("ReadDir", func(t *testing.T) { testReadDir(t, factory, dir, list) })
+	if len(file) > 0 {
+		t.Run("Reopen", func(t *testing.T) { testReopen(t, factory, file[0]) })
+		t.Run("Read", func(t *testing.T) { testRead(t, factory, file[0]) })
+	}
+	t.Run("Cleanup", func(t *testing.T) { testCleanup(t, factory, dir) })
+	t.Run("IsDir", func(t *testing.T) { testIsDir(t, list) })
+	for _, isopath := range iso {
+		var isopath = isopath
+		t.Run("ISO="+path.Base(isopath), func(t *testing.T) {
+			TestJoint(t, func() (jnt.Joint, error) {
+				var base, err = factory()
+				if err != nil {
+					return nil, err
+				}
+				var j = &jnt.IsoJoint{}
+				if err = j.Make(base, isopath); err != nil {
+					base.Cleanup()
+					return nil, err
+				}
+				return j, nil
+			}, "")
+		})
+	}
+}
+
+// isiso returns whether file with given name is ISO-image.
+func isiso(name string) bool {
+	return strings.ToLower(path.Ext(name)) == ".iso"
+}
+
+// isrealdir returns whether directory entry is not a container.
+func isrealdir(de fs.DirEntry) bool {
+	if jfi, ok := de.(interface{ IsRealDir() bool }); ok {
+		return jfi.IsRealDir()
+	}
+	return de.IsDir()
+}
+
+// makejoint calls factory and cleans up joint at the end of test.
+func makejoint(t *testing.T, factory Factory) jnt.Joint {
+	t.Helper()
+	var j, err = factory()
+	if err != nil {
+		t.Fatalf("can not make joint: %v", err)
+	}
+	t.Cleanup(func() { j.Cleanup() })
+	return j
+}
+
+// readdir returns whole content of directory.
+func readdir(t *testing.T, factory Factory, dir string) []fs.DirEntry {
+	t.Helper()
+	var j = makejoint(t, factory)
+	if _, err := j.Open(dir); err != nil {
+		t.Fatalf("can not open directory '%s': %v", dir, err)
+	}
+	defer j.Close()
+	var list, err = j.ReadDir(-1)
+	if err != nil {
+		t.Fatalf("can not read directory '%s': %v", dir, err)
+	}
+	return list
+}
+
+// names returns sorted names of directory entries.
+func names(list []fs.DirEntry) []string {
+	var s = make([]string, len(list))
+	for i, de := range list {
+		s[i] = de.Name()
+	}
+	sort.Strings(s)
+	return s
+}
+
+func testBusy(t *testing.T, factory Factory, dir string) {
+	var j = makejoint(t, factory)
+	if j.Busy() {
+		t.Fatal("joint is busy before opening")
+	}
+	var f, err = j.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !j.Busy() {
+		t.Fatal("joint is not busy after opening")
+	}
+	if _, err = j.Open(dir); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("opening of busy joint should return fs.ErrExist, got %v", err)
+	}
+	if !j.Busy() {
+		t.Fatal("joint is not busy after failed opening")
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if j.Busy() {
+		t.Fatal("joint is busy after closing")
+	}
+	// joint can be opened again after closing
+	if _, err = j.Open(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err = j.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testReadDir(t *testing.T, factory Factory, dir string, list []fs.DirEntry) {
+	var j = makejoint(t, factory)
+	for _, n := range []int{1, 2, len(list) + 1} {
+		if _, err := j.Open(dir); err != nil {
+			t.Fatal(err)
+		}
+		var all []fs.DirEntry
+		for {
+			var page, err = j.ReadDir(n)
+			if len(page) > n {
+				j.Close()
+				t.Fatalf("ReadDir(%d) returns %d entries", n, len(page))
+			}
+			all = append(all, page...)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				j.Close()
+				t.Fatalf("ReadDir(%d): %v", n, err)
+			}
+			if len(page) == 0 {
+				j.Close()
+				t.Fatalf("ReadDir(%d) returns no entries without io.EOF", n)
+			}
+			if len(all) > len(list) {
+				j.Close()
+				t.Fatalf("ReadDir(%d) returns more entries than ReadDir(-1)", n)
+			}
+		}
+		// reading after the end returns io.EOF again
+		if page, err := j.ReadDir(n); err != io.EOF || len(page) != 0 {
+			j.Close()
+			t.Fatalf("ReadDir(%d) after the end should return io.EOF, got %d entries and %v", n, len(page), err)
+		}
+		j.Close()
+		var s1, s2 = names(list), names(all)
+		if strings.Join(s1, "/") != strings.Join(s2, "/") {
+			t.Fatalf("ReadDir(%d) returns %v, expected %v", n, s2, s1)
+		}
+	}
+}
+
+func testReopen(t *testing.T, factory Factory, fpath string) {
+	var j = makejoint(t, factory)
+	var b1, b2 [chunk]byte
+	if _, err := j.Open(fpath); err != nil {
+		t.Fatal(err)
+	}
+	var n1, err = io.ReadFull(j, b1[:])
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		j.Close()
+		t.Fatal(err)
+	}
+	if err = j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = j.Open(fpath); err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	var n2 int
+	if n2, err = io.ReadFull(j, b2[:]); err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b1[:n1], b2[:n2]) {
+		t.Fatalf("file '%s' is not read from the beginning after reopening", fpath)
+	}
+}
+
+func testRead(t *testing.T, factory Factory, fpath string) {
+	var j = makejoint(t, factory)
+	var f, err = j.Open(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	var fi fs.FileInfo
+	if fi, err = f.Stat(); err != nil {
+		t.Fatal(err)
+	}
+	if fi.IsDir() {
+		t.Fatalf("file '%s' is directory", fpath)
+	}
+	var size int64
+	if size, err = j.Size(); err != nil {
+		t.Fatal(err)
+	}
+	if size != fi.Size() {
+		t.Fatalf("size of file '%s' is %d, file info has %d", fpath, size, fi.Size())
+	}
+	var data []byte
+	if data, err = io.ReadAll(j); err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(data)) != size {
+		t.Fatalf("read %d bytes of file '%s' with size %d", len(data), fpath, size)
+	}
+	if size == 0 {
+		return
+	}
+
+	// read last part of file by ReadAt
+	var off = size / 2
+	var b = make([]byte, size-off)
+	var n int
+	if n, err = j.ReadAt(b, off); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b[:n], data[off:]) {
+		t.Fatalf("ReadAt at offset %d of file '%s' does not match to content", off, fpath)
+	}
+	// read it again after Seek
+	var pos int64
+	if pos, err = j.Seek(off, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if pos != off {
+		t.Fatalf("Seek returns position %d, expected %d", pos, off)
+	}
+	if b, err = io.ReadAll(j); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, data[off:]) {
+		t.Fatalf("Read after Seek at offset %d of file '%s' does not match to content", off, fpath)
+	}
+}
+
+func testCleanup(t *testing.T, factory Factory, dir string) {
+	var j, err = factory()
+	if err != nil {
+		t.Fatalf("can not make joint: %v", err)
+	}
+	if _, err = j.Open(dir); err != nil {
+		j.Cleanup()
+		t.Fatal(err)
+	}
+	// Cleanup closes opened file
+	if err = j.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if j.Busy() {
+		t.Fatal("joint is busy after cleanup")
+	}
+	if err = j.Cleanup(); err != nil {
+		t.Fatalf("second cleanup returns error: %v", err)
+	}
+}
+
+func testIsDir(t *testing.T, list []fs.DirEntry) {
+	for _, de := range list {
+		if !isiso(de.Name()) {
+			continue
+		}
+		if !de.IsDir() {
+			t.Errorf("ISO-image '%s' should be recognized as virtual folder", de.Name())
+		}
+		if fi, err := de.Info(); err != nil {
+			t.Error(err)
+		} else if fi.Mode().Type() != fs.ModeDir {
+			t.Errorf("ISO-image '%s' have wrong file type", de.Name())
+		}
+		if isrealdir(de) {
+			t.Errorf("ISO-image '%s' should be recognized as real file", de.Name())
+		}
+	}
+}
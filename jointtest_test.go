@@ -0,0 +1,64 @@
+package joint_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+	"github.com/schwarzlichtbezirk/joint/jointtest"
+)
+
+// maker returns factory of joints with given type made at given address.
+func maker(joint func() jnt.Joint, addr string) jointtest.Factory {
+	return func() (jnt.Joint, error) {
+		var j = joint()
+		if err := j.Make(nil, addr); err != nil {
+			return nil, err
+		}
+		return j, nil
+	}
+}
+
+// Check up contract of joints on primary file system.
+func TestJointContract(t *testing.T) {
+	t.Run("Sys", func(t *testing.T) {
+		jointtest.TestJoint(t, maker(func() jnt.Joint { return &jnt.SysJoint{} }, ""), "testdata")
+	})
+	t.Run("FS", func(t *testing.T) {
+		jointtest.TestJoint(t, maker(func() jnt.Joint { return &jnt.FSJoint{FS: os.DirFS(".")} }, ""), "testdata")
+	})
+	t.Run("Img", func(t *testing.T) {
+		jointtest.TestJoint(t, maker(func() jnt.Joint { return &jnt.ImgJoint{} }, diskimg), "p3/disk")
+	})
+	for arcpath, joint := range arcjoints {
+		t.Run(arcpath, func(t *testing.T) {
+			jointtest.TestJoint(t, maker(joint, arcpath), "data")
+		})
+	}
+}
+
+// Check up contract of joints on network services.
+func TestJointContractNet(t *testing.T) {
+	for _, b := range backends() {
+		t.Run(b.name, func(t *testing.T) {
+			var addr = b.address()
+			if addr == "" {
+				t.Skipf("environment variable %s does not set, test on %s joints skipped", b.env, b.name)
+			}
+			jointtest.TestJoint(t, maker(b.joint, addr), "testdata")
+		})
+	}
+
+	t.Run("HTTP", func(t *testing.T) {
+		var srv = httptest.NewServer(http.FileServer(http.Dir(".")))
+		defer srv.Close()
+		jointtest.TestJoint(t, maker(func() jnt.Joint { return &jnt.HttpJoint{} }, srv.URL), "testdata")
+	})
+	t.Run("S3", func(t *testing.T) {
+		var srv = newFakeS3(t)
+		defer srv.Close()
+		jointtest.TestJoint(t, maker(func() jnt.Joint { return &jnt.S3Joint{} }, s3addr(srv)), "testdata")
+	})
+}
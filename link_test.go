@@ -0,0 +1,150 @@
+package joint_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// ISO-image "testdata/links.iso" has Rock Ridge extension
+// with symbolic links.
+const linksiso = "testdata/links.iso"
+
+// Symbolic links at ISO-image and their targets.
+var isolinks = map[string]string{
+	"fox.lnk":      "./fox.txt",
+	"abs.lnk":      "/docs/doc1.txt",
+	"docs.lnk":     "docs",
+	"docs/fox.lnk": "../fox.txt",
+	"loop1":        "loop2",
+	"loop2":        "loop1",
+}
+
+// Files reachable through symbolic links and their sizes.
+var linkfiles = map[string]int64{
+	"fox.lnk":           foxsize,
+	"abs.lnk":           445,
+	"docs.lnk/doc1.txt": 445,
+	"docs.lnk/fox.lnk":  foxsize,
+	"docs/fox.lnk":      foxsize,
+}
+
+func TestIsoReadLink(t *testing.T) {
+	var j = &jnt.IsoJoint{}
+	if err := j.Make(nil, linksiso); err != nil {
+		t.Fatal(err)
+	}
+	defer j.Cleanup()
+
+	for fpath, target := range isolinks {
+		var fi, err = j.Lstat(fpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			t.Fatalf("%s: file is not symbolic link", fpath)
+		}
+		var s string
+		if s, err = j.ReadLink(fpath); err != nil {
+			t.Fatal(err)
+		}
+		if s != target {
+			t.Fatalf("%s: expected link target '%s', got '%s'", fpath, target, s)
+		}
+	}
+	if _, err := j.ReadLink("fox.txt"); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatal("regular file should not be read as link")
+	}
+}
+
+func TestPoolLinkFollow(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	// links are not followed at ISO-image natively
+	if _, err := jp.Stat(linksiso + "/docs.lnk/doc1.txt"); err == nil {
+		t.Fatal("path with link should not be found without links following")
+	}
+
+	jnt.Cfg.LinkPolicy = jnt.LinkFollow
+	defer func() { jnt.Cfg.LinkPolicy = jnt.LinkNative }()
+
+	for fpath, size := range linkfiles {
+		var fi, err = jp.Stat(linksiso + "/" + fpath)
+		if err != nil {
+			t.Fatalf("%s: %v", fpath, err)
+		}
+		if fi.Size() != size {
+			t.Fatalf("%s: file size does not match", fpath)
+		}
+	}
+
+	var fi, err = jp.Lstat(linksiso + "/fox.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&fs.ModeSymlink == 0 {
+		t.Fatal("Lstat should not follow the link")
+	}
+
+	if _, err = jp.Open(linksiso + "/loop1"); !errors.Is(err, jnt.ErrLinkLoop) {
+		t.Fatalf("expected links loop error, got %v", err)
+	}
+
+	var sp = jnt.NewSubPool(jp, linksiso)
+	var target string
+	if target, err = sp.ReadLink("docs/fox.lnk"); err != nil {
+		t.Fatal(err)
+	}
+	if target != "../fox.txt" {
+		t.Fatal("link target does not match")
+	}
+}
+
+func TestSysLinks(t *testing.T) {
+	var root = filepath.ToSlash(t.TempDir())
+	if err := os.Symlink("../testdata", filepath.Join(root, "data")); err != nil {
+		t.Skip("symbolic links are not supported:", err)
+	}
+	var wd, _ = os.Getwd()
+	if err := os.Symlink(filepath.Join(wd, "testdata", "external.iso"), filepath.Join(root, "disk.iso")); err != nil {
+		t.Fatal(err)
+	}
+
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var target, err = jp.ReadLink(root + "/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "../testdata" {
+		t.Fatal("link target does not match")
+	}
+
+	// ISO-image pointed by link is opened as container
+	var fi fs.FileInfo
+	if fi, err = jp.Stat(root + "/disk.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != foxsize {
+		t.Fatal("file size does not match")
+	}
+
+	jnt.Cfg.LinkPolicy = jnt.LinkDeny
+	defer func() { jnt.Cfg.LinkPolicy = jnt.LinkNative }()
+
+	if _, err = jp.Open(root + "/disk.iso"); !errors.Is(err, jnt.ErrLinkDenied) {
+		t.Fatalf("expected denied link error, got %v", err)
+	}
+	if fi, err = jp.Lstat(root + "/data"); err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&fs.ModeSymlink == 0 {
+		t.Fatal("file is not symbolic link")
+	}
+}
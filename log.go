@@ -0,0 +1,111 @@
+package joint
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// discard is slog.Handler that drops all records.
+type discard struct{}
+
+func (discard) Enabled(context.Context, slog.Level) bool  { return false }
+func (discard) Handle(context.Context, slog.Record) error { return nil }
+func (h discard) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discard) WithGroup(string) slog.Handler           { return h }
+
+// nolog is default logger that writes nothing.
+var nolog = slog.New(discard{})
+
+// WithLogger sets logger of all caches at JointPool and of servers
+// that share the pool. Records are not written by default.
+func WithLogger(l *slog.Logger) PoolOption {
+	return func(jp *JointPool) {
+		jp.log = l
+	}
+}
+
+// logger returns logger of the pool.
+func (jp *JointPool) logger() *slog.Logger {
+	if jp.log == nil {
+		return nolog
+	}
+	return jp.log
+}
+
+// SetLogger sets logger of cache events. It should be set before
+// cache usage, JointPool sets it by WithLogger option. Records
+// have attributes with key of the cache without password, and with
+// scheme and host of network resource.
+func (jc *JointCache) SetLogger(l *slog.Logger) {
+	if l == nil {
+		jc.log = nolog
+		return
+	}
+	jc.log = l.With(keyattrs(jc.key)...)
+}
+
+// Redact returns key or URL without password of user.
+func Redact(key string) string {
+	var i = strings.Index(key, "://")
+	if i == -1 {
+		return key
+	}
+	var host = key[i+3:]
+	if j := strings.IndexByte(host, '/'); j != -1 {
+		host = host[:j]
+	}
+	var at = strings.LastIndexByte(host, '@')
+	if at == -1 {
+		return key
+	}
+	var user, _, ok = strings.Cut(host[:at], ":")
+	if !ok {
+		return key
+	}
+	return key[:i+3] + user + key[i+3+at:]
+}
+
+// makelog writes records of making stages of network joints,
+// such as dialing and login, so failure of each stage can be told
+// apart. It's set to joint by tracemake with tracer of stages.
+type makelog struct {
+	log    *slog.Logger
+	failed bool // some stage was failed and logged
+}
+
+// stagespan is Span of making stage that writes record at its end.
+type stagespan struct {
+	Span
+	ml    *makelog
+	stage string
+	t0    time.Time
+}
+
+func (s *stagespan) End(err error) {
+	s.Span.End(err)
+	var d = time.Since(s.t0)
+	if err != nil {
+		s.ml.failed = true
+		s.ml.log.Warn("joint "+s.stage+" failed", "duration", d, "err", err)
+	} else {
+		s.ml.log.Debug("joint "+s.stage+" done", "duration", d)
+	}
+}
+
+// keyattrs returns logger attributes for given key of cache.
+func keyattrs(key string) []any {
+	var args = []any{slog.String("key", Redact(key))}
+	if i := strings.Index(key, "://"); i != -1 {
+		var addr, _, _ = SplitUrl(key)
+		var host = addr[i+3:]
+		if at := strings.LastIndexByte(host, '@'); at != -1 {
+			host = host[at+1:]
+		}
+		args = append(args,
+			slog.String("scheme", strings.ToLower(key[:i])),
+			slog.String("host", host))
+	}
+	return args
+}
@@ -0,0 +1,166 @@
+package joint
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Observer receives events of joints caches to collect metrics.
+// Methods are called synchronously by goroutines that use joints,
+// so they should be fast and safe for concurrent use.
+type Observer interface {
+	// New joint was made for given key with given latency, or failed.
+	OnDial(key string, d time.Duration, err error)
+	// Joint was taken from the cache instead of making new one.
+	OnReuse(key string)
+	// Joint was returned to the cache.
+	OnPut(key string)
+	// Cached joint was cleaned up on expiration.
+	OnExpire(key string)
+	// Joint was dropped after failure with given error.
+	OnError(key string, err error)
+	// Data was read from joint with given latency.
+	OnRead(key string, n int, d time.Duration)
+}
+
+// WithObserver sets observer of events of all caches at JointPool.
+func WithObserver(obs Observer) PoolOption {
+	return func(jp *JointPool) {
+		jp.obs = obs
+	}
+}
+
+// SetObserver sets observer of cache events. It should be
+// set before cache usage, JointPool sets it by WithObserver option.
+func (jc *JointCache) SetObserver(obs Observer) {
+	jc.obs = obs
+}
+
+// CacheStats is snapshot of counters of JointCache events.
+type CacheStats struct {
+	Free       int           `json:"free" yaml:"free" xml:"free"`                      // number of joints at the cache
+	Dials      int64         `json:"dials" yaml:"dials" xml:"dials"`                   // number of made joints
+	DialErrors int64         `json:"dial-errors" yaml:"dial-errors" xml:"dial-errors"` // number of failed makes
+	DialTime   time.Duration `json:"dial-time" yaml:"dial-time" xml:"dial-time"`       // total time of makes
+	Reuses     int64         `json:"reuses" yaml:"reuses" xml:"reuses"`                // number of joints taken from the cache
+	Puts       int64         `json:"puts" yaml:"puts" xml:"puts"`                      // number of joints returned to the cache
+	Expires    int64         `json:"expires" yaml:"expires" xml:"expires"`             // number of expired joints
+	Errors     int64         `json:"errors" yaml:"errors" xml:"errors"`                // number of joints dropped after failure
+	Reads      int64         `json:"reads" yaml:"reads" xml:"reads"`                   // number of read calls
+	ReadBytes  int64         `json:"read-bytes" yaml:"read-bytes" xml:"read-bytes"`    // number of read bytes
+	ReadTime   time.Duration `json:"read-time" yaml:"read-time" xml:"read-time"`       // total time of read calls
+}
+
+// HitRate returns part of joints taken from the cache
+// among all joints got from it.
+func (cs CacheStats) HitRate() float64 {
+	if cs.Reuses+cs.Dials == 0 {
+		return 0
+	}
+	return float64(cs.Reuses) / float64(cs.Reuses+cs.Dials)
+}
+
+// jointstats has counters of JointCache events.
+type jointstats struct {
+	dials, dialerrs, dialtime  atomic.Int64
+	reuses, puts, expires      atomic.Int64
+	errors                     atomic.Int64
+	reads, readbytes, readtime atomic.Int64
+}
+
+// Stats returns snapshot of cache counters.
+func (jc *JointCache) Stats() CacheStats {
+	return CacheStats{
+		Free:       jc.Count(),
+		Dials:      jc.stats.dials.Load(),
+		DialErrors: jc.stats.dialerrs.Load(),
+		DialTime:   time.Duration(jc.stats.dialtime.Load()),
+		Reuses:     jc.stats.reuses.Load(),
+		Puts:       jc.stats.puts.Load(),
+		Expires:    jc.stats.expires.Load(),
+		Errors:     jc.stats.errors.Load(),
+		Reads:      jc.stats.reads.Load(),
+		ReadBytes:  jc.stats.readbytes.Load(),
+		ReadTime:   time.Duration(jc.stats.readtime.Load()),
+	}
+}
+
+// Stats returns snapshot of counters of each cache at the pool.
+func (jp *JointPool) Stats() map[string]CacheStats {
+	jp.jpmux.RLock()
+	defer jp.jpmux.RUnlock()
+
+	var stats = make(map[string]CacheStats, len(jp.jpmap))
+	for key, jc := range jp.jpmap {
+		stats[key] = jc.Stats()
+	}
+	return stats
+}
+
+// ondial is called when joint was made with given duration.
+// Failure is logged if it was not logged already by making stage,
+// so it's failure of protocol after dialing and login.
+func (jc *JointCache) ondial(d time.Duration, err error, logged bool) {
+	if err != nil {
+		jc.stats.dialerrs.Add(1)
+		if !logged {
+			jc.log.Warn("joint make failed", "duration", d, "err", err)
+		}
+	} else {
+		jc.stats.dials.Add(1)
+		jc.stats.dialtime.Add(int64(d))
+		jc.log.Debug("joint dialed", "duration", d)
+	}
+	if jc.obs != nil {
+		jc.obs.OnDial(jc.key, d, err)
+	}
+}
+
+func (jc *JointCache) onreuse() {
+	jc.stats.reuses.Add(1)
+	jc.log.Debug("joint reused")
+	if jc.obs != nil {
+		jc.obs.OnReuse(jc.key)
+	}
+}
+
+func (jc *JointCache) onput() {
+	jc.stats.puts.Add(1)
+	if jc.obs != nil {
+		jc.obs.OnPut(jc.key)
+	}
+}
+
+func (jc *JointCache) onexpire(err error) {
+	jc.stats.expires.Add(1)
+	if err != nil {
+		jc.log.Warn("cleanup of expired joint failed", "err", err)
+	} else {
+		jc.log.Debug("joint expired")
+	}
+	if jc.obs != nil {
+		jc.obs.OnExpire(jc.key)
+	}
+}
+
+// onerror is called when joint was failed with given error,
+// and it was dropped with given error of cleanup.
+func (jc *JointCache) onerror(err, err1 error) {
+	jc.stats.errors.Add(1)
+	jc.log.Warn("joint dropped after failure", "err", err)
+	if err1 != nil {
+		jc.log.Warn("cleanup of dropped joint failed", "err", err1)
+	}
+	if jc.obs != nil {
+		jc.obs.OnError(jc.key, err)
+	}
+}
+
+func (jc *JointCache) onread(n int, d time.Duration) {
+	jc.stats.reads.Add(1)
+	jc.stats.readbytes.Add(int64(n))
+	jc.stats.readtime.Add(int64(d))
+	if jc.obs != nil {
+		jc.obs.OnRead(jc.key, n, d)
+	}
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"expvar"
+	"sync"
+	"time"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// Expvar is joint.Observer that publishes counters of each cache at
+// expvar map with nested map for each key of cache. Latencies are
+// published as total number of nanoseconds.
+type Expvar struct {
+	m   *expvar.Map
+	mux sync.Mutex
+}
+
+// NewExpvar returns observer that publishes counters at expvar
+// variable with given name. Like expvar.Publish,
+// it panics if the name is already registered.
+func NewExpvar(name string) *Expvar {
+	return &Expvar{m: expvar.NewMap(name)}
+}
+
+// Map returns expvar map with published counters.
+func (e *Expvar) Map() *expvar.Map {
+	return e.m
+}
+
+// key returns map of counters for cache with given key.
+func (e *Expvar) key(key string) *expvar.Map {
+	key = jnt.Redact(key)
+	if v, ok := e.m.Get(key).(*expvar.Map); ok {
+		return v
+	}
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if v, ok := e.m.Get(key).(*expvar.Map); ok {
+		return v
+	}
+	var v = new(expvar.Map).Init()
+	e.m.Set(key, v)
+	return v
+}
+
+func (e *Expvar) OnDial(key string, d time.Duration, err error) {
+	var m = e.key(key)
+	if err != nil {
+		m.Add("dial-errors", 1)
+		return
+	}
+	m.Add("dials", 1)
+	m.Add("dial-time", int64(d))
+}
+
+func (e *Expvar) OnReuse(key string) {
+	e.key(key).Add("reuses", 1)
+}
+
+func (e *Expvar) OnPut(key string) {
+	e.key(key).Add("puts", 1)
+}
+
+func (e *Expvar) OnExpire(key string) {
+	e.key(key).Add("expires", 1)
+}
+
+func (e *Expvar) OnError(key string, err error) {
+	e.key(key).Add("errors", 1)
+}
+
+func (e *Expvar) OnRead(key string, n int, d time.Duration) {
+	var m = e.key(key)
+	m.Add("reads", 1)
+	m.Add("read-bytes", int64(n))
+	m.Add("read-time", int64(d))
+}
@@ -0,0 +1,4 @@
+// Package metrics provides adapters of joint.Observer interface
+// that publish events of joints caches to expvar and Prometheus.
+// Passwords at keys of caches are not published.
+package metrics
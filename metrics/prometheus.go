@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// Prometheus is joint.Observer that collects metrics of joints caches
+// labeled by key of cache. It implements prometheus.Collector,
+// so it should be registered to be exported.
+type Prometheus struct {
+	dials    *prometheus.CounterVec
+	dialtime *prometheus.HistogramVec
+	reuses   *prometheus.CounterVec
+	puts     *prometheus.CounterVec
+	expires  *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	bytes    *prometheus.CounterVec
+	readtime *prometheus.HistogramVec
+}
+
+// NewPrometheus returns observer with metrics at given namespace,
+// i.e. "joint_dials_total" for "joint" namespace.
+func NewPrometheus(namespace string) *Prometheus {
+	var counter = func(name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+		}, append([]string{"key"}, labels...))
+	}
+	var histogram = func(name, help string) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 4, 10),
+		}, []string{"key"})
+	}
+	return &Prometheus{
+		dials:    counter("dials_total", "Number of made joints by result.", "result"),
+		dialtime: histogram("dial_duration_seconds", "Time to make new joint."),
+		reuses:   counter("reuses_total", "Number of joints taken from cache."),
+		puts:     counter("puts_total", "Number of joints returned to cache."),
+		expires:  counter("expires_total", "Number of cached joints cleaned up on expiration."),
+		errors:   counter("errors_total", "Number of joints dropped after failure."),
+		bytes:    counter("read_bytes_total", "Number of bytes read from joints."),
+		readtime: histogram("read_duration_seconds", "Time of read calls on joints."),
+	}
+}
+
+// collectors returns all metrics of observer.
+func (p *Prometheus) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		p.dials, p.dialtime, p.reuses, p.puts,
+		p.expires, p.errors, p.bytes, p.readtime,
+	}
+}
+
+// Describe implements prometheus.Collector interface.
+func (p *Prometheus) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range p.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector interface.
+func (p *Prometheus) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range p.collectors() {
+		c.Collect(ch)
+	}
+}
+
+func (p *Prometheus) OnDial(key string, d time.Duration, err error) {
+	key = jnt.Redact(key)
+	if err != nil {
+		p.dials.WithLabelValues(key, "error").Inc()
+		return
+	}
+	p.dials.WithLabelValues(key, "ok").Inc()
+	p.dialtime.WithLabelValues(key).Observe(d.Seconds())
+}
+
+func (p *Prometheus) OnReuse(key string) {
+	p.reuses.WithLabelValues(jnt.Redact(key)).Inc()
+}
+
+func (p *Prometheus) OnPut(key string) {
+	p.puts.WithLabelValues(jnt.Redact(key)).Inc()
+}
+
+func (p *Prometheus) OnExpire(key string) {
+	p.expires.WithLabelValues(jnt.Redact(key)).Inc()
+}
+
+func (p *Prometheus) OnError(key string, err error) {
+	p.errors.WithLabelValues(jnt.Redact(key)).Inc()
+}
+
+func (p *Prometheus) OnRead(key string, n int, d time.Duration) {
+	key = jnt.Redact(key)
+	p.bytes.WithLabelValues(key).Add(float64(n))
+	p.readtime.WithLabelValues(key).Observe(d.Seconds())
+}
@@ -0,0 +1,76 @@
+package joint_test
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// countobs is observer that counts events by names.
+type countobs struct {
+	events map[string]int
+	mux    sync.Mutex
+}
+
+func (o *countobs) add(event string) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	o.events[event]++
+}
+
+func (o *countobs) OnDial(key string, d time.Duration, err error) { o.add("dial") }
+func (o *countobs) OnReuse(key string)                            { o.add("reuse") }
+func (o *countobs) OnPut(key string)                              { o.add("put") }
+func (o *countobs) OnExpire(key string)                           { o.add("expire") }
+func (o *countobs) OnError(key string, err error)                 { o.add("error") }
+func (o *countobs) OnRead(key string, n int, d time.Duration)     { o.add("read") }
+
+func TestPoolStats(t *testing.T) {
+	var obs = &countobs{events: map[string]int{}}
+	var jp = jnt.NewJointPool(jnt.WithObserver(obs))
+	defer jp.Close()
+
+	for i := 0; i < 2; i++ {
+		var f, err = jp.Open("testdata/external.iso/fox.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = io.ReadAll(f.(io.Reader))
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var cs, ok = jp.Stats()["testdata/external.iso"]
+	if !ok {
+		t.Fatal("statistics of cache is not found")
+	}
+	if cs.Dials != 1 || cs.Reuses != 1 || cs.Puts != 2 || cs.Free != 1 {
+		t.Fatalf("unexpected counters: %+v", cs)
+	}
+	if cs.ReadBytes != 2*foxsize || cs.Reads == 0 {
+		t.Fatalf("unexpected read counters: %+v", cs)
+	}
+	if cs.HitRate() != 0.5 {
+		t.Fatalf("unexpected hit rate %g", cs.HitRate())
+	}
+	if obs.events["dial"] != 1 || obs.events["reuse"] != 1 || obs.events["put"] != 2 || obs.events["read"] != int(cs.Reads) {
+		t.Fatalf("unexpected events: %v", obs.events)
+	}
+
+	// joint failed with injected fault is dropped
+	var fp = jnt.NewJointPool(jnt.WithFaults(&jnt.Faults{
+		FailOn: map[string][]int{"Open": {1}},
+	}))
+	defer fp.Close()
+	if _, err := fp.Open("testdata/external.iso/fox.txt"); err == nil {
+		t.Fatal("injected fault expected")
+	}
+	if cs = fp.Stats()["testdata/external.iso"]; cs.Errors != 1 || cs.Free != 0 {
+		t.Fatalf("unexpected counters: %+v", cs)
+	}
+}
@@ -1,10 +1,77 @@
 package joint
 
 import (
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 )
 
+// JointMaker returns new empty joint for container file, such as
+// ISO-image, disk image or archive.
+type JointMaker func() Joint
+
+// contmap is global map of container joints makers by files extensions.
+var contmap = map[string]JointMaker{
+	".iso": func() Joint { return &IsoJoint{} },
+	".img": func() Joint { return &ImgJoint{} },
+	".zip": func() Joint { return &ZipJoint{} },
+	".7z":  func() Joint { return &SevenZipJoint{} },
+	".rar": func() Joint { return &RarJoint{} },
+	".tar": func() Joint { return &TarJoint{} },
+}
+var contmux sync.RWMutex
+
+// RegisterContainer associates joint maker with given file extension
+// with leading dot. Extensions are case-insensitive. Files with this
+// extension are considered as folders, and MakeJoint opens them by
+// joint returned by maker.
+func RegisterContainer(ext string, maker JointMaker) {
+	contmux.Lock()
+	contmap[strings.ToLower(ext)] = maker
+	contmux.Unlock()
+}
+
+// UnregisterContainer removes given file extension from the set
+// of containers, so files with this extension becomes regular files.
+func UnregisterContainer(ext string) {
+	contmux.Lock()
+	delete(contmap, strings.ToLower(ext))
+	contmux.Unlock()
+}
+
+// ContainerExts returns sorted list of registered containers extensions.
+func ContainerExts() []string {
+	contmux.RLock()
+	var list = make([]string, 0, len(contmap))
+	for ext := range contmap {
+		list = append(list, ext)
+	}
+	contmux.RUnlock()
+	sort.Strings(list)
+	return list
+}
+
+// ContainerMaker returns joint maker for container file pointed by
+// given path, or nil if file extension is not registered. Extension
+// is recognized without case sensitivity.
+func ContainerMaker(fpath string) JointMaker {
+	var ext = path.Ext(fpath)
+	if ext == "" {
+		return nil
+	}
+	contmux.RLock()
+	defer contmux.RUnlock()
+	return contmap[strings.ToLower(ext)]
+}
+
+// IsTypeContainer checks that endpoint-file in given path has
+// extension of registered container.
+func IsTypeContainer(fpath string) bool {
+	return ContainerMaker(fpath) != nil
+}
+
 // HasFoldPrefix tests whether the string s begins with prefix
 // without case sensitivity.
 func HasFoldPrefix(s, prefix string) bool {
@@ -34,11 +101,7 @@ func JoinPath(dir, base string) string {
 
 // IsTypeIso checks that endpoint-file in given path has ISO-extension.
 func IsTypeIso(fpath string) bool {
-	if len(fpath) < 4 {
-		return false
-	}
-	var ext = fpath[len(fpath)-4:]
-	return ext == ".iso" || ext == ".ISO"
+	return strings.EqualFold(path.Ext(fpath), ".iso")
 }
 
 // SplitUrl splits URL to address string and to path as is.
@@ -63,17 +126,26 @@ func SplitUrl(urlpath string) (string, string, bool) {
 // remained local path. Also returns boolean value that given path
 // is not at primary file system.
 func SplitKey(fullpath string) (string, string, bool) {
-	if IsTypeIso(fullpath) {
-		return fullpath, "", true
-	}
-	var p = max(
-		strings.LastIndex(fullpath, ".iso/"),
-		strings.LastIndex(fullpath, ".ISO/"))
-	if p != -1 {
-		return fullpath[:p+4], fullpath[p+5:], true
+	return splitkey(nil, fullpath, len(fullpath))
+}
+
+// splitkey is the same as SplitKey, but recognizes only containers
+// which path ends not after given position. It helps to skip real
+// directories with containers extensions. Files recognized as
+// containers by content with results at given cache also are considered.
+func splitkey(sc *sniffcache, fullpath string, end int) (string, string, bool) {
+	for p := end; p > 0; p-- {
+		if (p == len(fullpath) || fullpath[p] == '/') &&
+			(IsTypeContainer(fullpath[:p]) || sc.maker(fullpath[:p]) != nil) {
+			return fullpath[:p], strings.TrimPrefix(fullpath[p:], "/"), true
+		}
 	}
 	var key, fpath, isurl = SplitUrl(fullpath)
 	if isurl {
+		if HasFoldPrefix(fullpath, "smb://") { // share name is the part of key
+			var share, rest, _ = strings.Cut(fpath, "/")
+			return key + "/" + share, rest, true
+		}
 		if HasFoldPrefix(fullpath, "http://") || HasFoldPrefix(fullpath, "https://") {
 			if root, ok := FindDavRoot(key, fpath); ok {
 				return key + root, fpath[len(root)-1:], true
@@ -1,9 +1,14 @@
 package joint
 
 import (
+	"context"
 	"errors"
+	"io"
 	"io/fs"
+	"log/slog"
+	"path"
 	"sort"
+	"strings"
 	"sync"
 )
 
@@ -11,14 +16,28 @@ import (
 // Each key in map is address or path to file system resource,
 // value - cached for this resource list of joints.
 type JointPool struct {
-	jpmap map[string]*JointCache
-	jpmux sync.RWMutex
+	jpmap   map[string]*JointCache
+	real    map[string]struct{} // keys that point to real directories
+	sniffed sniffcache          // results of content recognition
+	jpmux   sync.RWMutex
+	faults  *Faults      // failures injected into all joints
+	obs     Observer     // receiver of caches events
+	log     *slog.Logger // logger of caches events
+	tr      Tracer       // tracer of pool operations
 }
 
-func NewJointPool() *JointPool {
-	return &JointPool{
+// PoolOption configures JointPool at creation.
+type PoolOption func(jp *JointPool)
+
+func NewJointPool(opts ...PoolOption) *JointPool {
+	var jp = &JointPool{
 		jpmap: map[string]*JointCache{},
+		real:  map[string]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(jp)
 	}
+	return jp
 }
 
 // Keys returns list of all joints key paths.
@@ -43,6 +62,15 @@ func (jp *JointPool) GetCache(key string) (jc *JointCache) {
 	var ok bool
 	if jc, ok = jp.jpmap[key]; !ok {
 		jc = NewJointCache(key)
+		jc.faults = jp.faults
+		jc.obs = jp.obs
+		jc.sniffed = &jp.sniffed
+		if jp.log != nil {
+			jc.SetLogger(jp.log)
+		}
+		if jp.tr != nil {
+			jc.SetTracer(jp.tr)
+		}
 		jp.jpmap[key] = jc
 	}
 	return
@@ -64,10 +92,33 @@ func (jp *JointPool) Close() error {
 // Clear is same as Close, and removes all entries in the map.
 func (jp *JointPool) Clear() error {
 	var err = jp.Close()
+	jp.jpmux.Lock()
 	clear(jp.jpmap)
+	clear(jp.real)
+	jp.jpmux.Unlock()
 	return err
 }
 
+// isrealdir returns true if given key was found as real directory
+// with name of container.
+func (jp *JointPool) isrealdir(key string) bool {
+	jp.jpmux.RLock()
+	defer jp.jpmux.RUnlock()
+	var _, ok = jp.real[key]
+	return ok
+}
+
+// setrealdir remembers that given key points to real directory,
+// so it will not be dialed again, and removes empty cache for it.
+func (jp *JointPool) setrealdir(key string) {
+	jp.jpmux.Lock()
+	defer jp.jpmux.Unlock()
+	jp.real[key] = struct{}{}
+	if jc, ok := jp.jpmap[key]; ok && jc.Count() == 0 {
+		delete(jp.jpmap, key)
+	}
+}
+
 // GetJoint returns joint for given key.
 func (jp *JointPool) GetJoint(key string) (j Joint, err error) {
 	return jp.GetCache(key).Get()
@@ -76,43 +127,336 @@ func (jp *JointPool) GetJoint(key string) (j Joint, err error) {
 // Open opens file with given full path to this file,
 // that can be located inside of nested ISO-images and/or
 // on FTP, SFTP, WebDAV servers.
+// If Cfg.SniffContent is set, files without registered container
+// extension are recognized by their content on first access, and
+// opened as containers if they are. Results are cached by full path.
+// Symbolic links are followed in accordance with Cfg.LinkPolicy.
 // Open implements fs.FS interface,
 // and returns file that can be casted to joint wrapper.
 func (jp *JointPool) Open(fullpath string) (f fs.File, err error) {
-	var key, fpath, isurl = SplitKey(fullpath)
-	if !isurl {
-		var j = &SysJoint{dir: key}
-		return j.Open(fpath)
+	return jp.OpenContext(context.Background(), fullpath)
+}
+
+// OpenContext is Open that traces operation by tracer of the pool
+// as nested span of given context.
+func (jp *JointPool) OpenContext(ctx context.Context, fullpath string) (f fs.File, err error) {
+	var span Span
+	ctx, span = jp.tracer().Start(ctx, "JointPool.Open", slog.String("path", Redact(fullpath)))
+	defer func() { span.End(err) }()
+
+	switch Cfg.LinkPolicy {
+	case LinkFollow:
+		if f, err = jp.sniffopen(ctx, fullpath); err == nil {
+			if fi, err1 := f.Stat(); err1 != nil || fi.Mode()&fs.ModeSymlink == 0 {
+				return
+			}
+			f.Close()
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+		var real string
+		if real, err = jp.EvalLinks(fullpath); err != nil {
+			return
+		}
+		return jp.sniffopen(ctx, real)
+	case LinkDeny:
+		if fi, err1 := jp.Lstat(fullpath); err1 == nil && fi.Mode()&fs.ModeSymlink != 0 {
+			return nil, &fs.PathError{Op: "open", Path: fullpath, Err: ErrLinkDenied}
+		}
 	}
-	var jc = jp.GetCache(key)
-	if f, err = jc.Open(fpath); err != nil {
+	return jp.sniffopen(ctx, fullpath)
+}
+
+// sniffopen is Open without following of symbolic links.
+func (jp *JointPool) sniffopen(ctx context.Context, fullpath string) (f fs.File, err error) {
+	if f, err = jp.open(ctx, fullpath); !Cfg.SniffContent {
+		return
+	}
+	if err == nil {
+		var read, cont = jp.sniff(strings.TrimSuffix(fullpath, "/"), f)
+		if s, ok := f.(io.Seeker); ok && read && !cont { // reading of content moves position of some joints
+			if _, err = s.Seek(0, io.SeekStart); err == nil {
+				return
+			}
+		}
+		if read { // container should be opened by its joint
+			f.Close()
+			return jp.open(ctx, fullpath)
+		}
 		return
 	}
+	if jp.sniffpath(ctx, fullpath) { // some parent file is container
+		return jp.sniffopen(ctx, fullpath)
+	}
 	return
 }
 
+// sniff recognizes content of opened file at given full path. It returns
+// true if content was read, and true if file is container. Results are
+// cached, and files that was already recognized, and directories are skipped.
+func (jp *JointPool) sniff(fullpath string, f fs.File) (read, cont bool) {
+	if jp.sniffed.has(fullpath) {
+		return
+	}
+	var fi, err = f.Stat()
+	if err != nil || fi.IsDir() {
+		return
+	}
+	var r, ok = f.(io.ReaderAt)
+	if !ok {
+		return
+	}
+	var maker JointMaker
+	maker, err = SniffContainer(r)
+	if err != nil {
+		return true, false
+	}
+	jp.sniffed.set(fullpath, maker)
+	return true, maker != nil
+}
+
+// sniffpath looks for the nearest existing parent of given full path,
+// recognizes its content, and returns true if it is container.
+func (jp *JointPool) sniffpath(ctx context.Context, fullpath string) bool {
+	var addr, _, _ = SplitUrl(fullpath)
+	for p := len(fullpath) - 1; p > len(addr); p-- {
+		if fullpath[p] != '/' {
+			continue
+		}
+		var dir = fullpath[:p]
+		if jp.sniffed.has(dir) {
+			return false
+		}
+		var f, err = jp.open(ctx, dir)
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		var _, cont = jp.sniff(dir, f)
+		return cont
+	}
+	return false
+}
+
+// open is Open without content recognition.
+func (jp *JointPool) open(ctx context.Context, fullpath string) (f fs.File, err error) {
+	var key, fpath, isurl = splitkey(&jp.sniffed, fullpath, len(fullpath))
+	for {
+		if len(fpath) > 1 { // directory can be pointed with slash
+			fpath = strings.TrimSuffix(fpath, "/")
+		}
+		if !isurl {
+			var j = &SysJoint{dir: key}
+			return j.Open(fpath)
+		}
+		if !jp.isrealdir(key) {
+			if f, err = jp.GetCache(key).open(ctx, fpath); !errors.Is(err, ErrRealDir) {
+				return
+			}
+			jp.setrealdir(key)
+		}
+		// key ends with real directory, so look for previous container
+		key, fpath, isurl = splitkey(&jp.sniffed, fullpath, len(key)-1)
+	}
+}
+
+// getjoint returns joint for the nearest container or file system
+// resource at given full path, and local path at this joint. Containers
+// which path ends after given position are not considered, so file of
+// container itself can be accessed.
+func (jp *JointPool) getjoint(fullpath string, end int) (jw JointWrap, fpath string, err error) {
+	var key string
+	var isurl bool
+	key, fpath, isurl = splitkey(&jp.sniffed, fullpath, end)
+	for {
+		if len(fpath) > 1 { // directory can be pointed with slash
+			fpath = strings.TrimSuffix(fpath, "/")
+		}
+		if !isurl {
+			jw.Joint = &SysJoint{dir: key}
+			return
+		}
+		if !jp.isrealdir(key) {
+			if jw, err = jp.GetCache(key).Get(); !errors.Is(err, ErrRealDir) {
+				return
+			}
+			jp.setrealdir(key)
+		}
+		// key ends with real directory, so look for previous container
+		key, fpath, isurl = splitkey(&jp.sniffed, fullpath, len(key)-1)
+	}
+}
+
+// release puts joint back to its cache, or drops it
+// if it was failed with unexpected error.
+func release(jw JointWrap, err error) {
+	if err == nil || errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+		jw.Close()
+	} else {
+		jw.drop(err)
+	}
+}
+
+// Lstat returns fs.FileInfo of file pointed by given full path
+// without following of symbolic link. If joint for this path
+// does not support links, it's the same as Stat. Containers
+// are described as files at the joint where they are placed.
+func (jp *JointPool) Lstat(fullpath string) (fi fs.FileInfo, err error) {
+	fullpath = strings.TrimSuffix(fullpath, "/")
+	var jw, fpath, err1 = jp.getjoint(fullpath, len(fullpath)-1)
+	if err1 != nil {
+		return nil, err1
+	}
+	defer func() { release(jw, err) }()
+
+	if lj, ok := jw.Joint.(LinkJoint); ok && fpath != "" && fpath != "." {
+		return lj.Lstat(fpath)
+	}
+	if _, err = jw.Open(fpath); err != nil {
+		return
+	}
+	return jw.Stat()
+}
+
+// ReadLink returns target of symbolic link pointed by given full path.
+// Target is returned as is, it can be relative to link directory.
+// ReadLink implements fs.ReadLinkFS interface.
+func (jp *JointPool) ReadLink(fullpath string) (target string, err error) {
+	fullpath = strings.TrimSuffix(fullpath, "/")
+	var jw, fpath, err1 = jp.getjoint(fullpath, len(fullpath)-1)
+	if err1 != nil {
+		return "", err1
+	}
+	defer func() { release(jw, err) }()
+
+	if lj, ok := jw.Joint.(LinkJoint); ok && fpath != "" && fpath != "." {
+		return lj.ReadLink(fpath)
+	}
+	return "", &fs.PathError{Op: "readlink", Path: fullpath, Err: fs.ErrInvalid}
+}
+
+// EvalLinks returns the path after the evaluation of all symbolic links
+// at given full path. Relative targets are resolved from the link
+// directory, absolute targets at containers and at remote resources
+// are resolved from root of joint that contains the link. Number of
+// resolved links is limited by Cfg.MaxLinkHops to break loops.
+func (jp *JointPool) EvalLinks(fullpath string) (string, error) {
+	var addr, todo, _ = SplitUrl(fullpath)
+	var root = addr
+	if strings.HasPrefix(todo, "/") {
+		root += "/"
+		todo = todo[1:]
+	}
+	var done = root
+	var hops int
+	for todo != "" {
+		var name string
+		name, todo, _ = strings.Cut(todo, "/")
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if i := strings.LastIndexByte(done, '/'); i >= len(root) {
+				done = done[:i]
+			} else {
+				done = root
+			}
+			continue
+		}
+		var next = JoinPath(done, name)
+		var fi, err = jp.Lstat(next)
+		if err != nil {
+			return "", err
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			done = next
+			continue
+		}
+		if hops++; hops > Cfg.MaxLinkHops {
+			return "", &fs.PathError{Op: "evallinks", Path: fullpath, Err: ErrLinkLoop}
+		}
+		var target string
+		if target, err = jp.ReadLink(next); err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(target, "/") {
+			if key, _, isurl := splitkey(&jp.sniffed, next, len(next)); isurl {
+				done = key
+			} else {
+				done = addr + "/"
+			}
+		}
+		todo = JoinPath(strings.TrimPrefix(target, "/"), todo)
+	}
+	return done, nil
+}
+
 // Stat returns fs.FileInfo of file pointed by given full path.
 // Stat implements fs.StatFS interface.
 func (jp *JointPool) Stat(fullpath string) (fi fs.FileInfo, err error) {
+	return jp.StatContext(context.Background(), fullpath)
+}
+
+// StatContext is Stat that traces operation by tracer of the pool
+// as nested span of given context.
+func (jp *JointPool) StatContext(ctx context.Context, fullpath string) (fi fs.FileInfo, err error) {
+	var tr = jp.tracer()
+	var span Span
+	ctx, span = tr.Start(ctx, "JointPool.Stat", slog.String("path", Redact(fullpath)))
+	defer func() { span.End(err) }()
+
 	var f fs.File
-	if f, err = jp.Open(fullpath); err != nil {
+	if f, err = jp.OpenContext(ctx, fullpath); err != nil {
 		return
 	}
 	defer f.Close()
-	return f.Stat()
+	ctx1, span1 := tr.Start(ctx, "Joint.Stat")
+	var prev = tracefile(f, ctx1)
+	fi, err = f.Stat()
+	tracefile(f, prev)
+	span1.End(err)
+	return
 }
 
 // ReadDir returns directory files fs.DirEntry list pointed by given full path.
 // ReadDir implements ReadDirFS interface.
 func (jp *JointPool) ReadDir(fullpath string) (list []fs.DirEntry, err error) {
+	return jp.ReadDirContext(context.Background(), fullpath)
+}
+
+// ReadDirContext is ReadDir that traces operation by tracer of the pool
+// as nested span of given context.
+func (jp *JointPool) ReadDirContext(ctx context.Context, fullpath string) (list []fs.DirEntry, err error) {
+	var tr = jp.tracer()
+	var span Span
+	ctx, span = tr.Start(ctx, "JointPool.ReadDir", slog.String("path", Redact(fullpath)))
+	defer func() { span.End(err) }()
+
 	var f fs.File
-	if f, err = jp.Open(fullpath); err != nil {
+	if f, err = jp.OpenContext(ctx, fullpath); err != nil {
 		return
 	}
 	defer f.Close()
 
+	ctx1, span1 := tr.Start(ctx, "Joint.ReadDir")
+	var prev = tracefile(f, ctx1)
 	list, err = f.(Joint).ReadDir(-1)
+	tracefile(f, prev)
+	span1.SetAttrs(slog.Int("count", len(list)))
+	span1.End(err)
 	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	if Cfg.SniffContent {
+		for i, de := range list {
+			if de == nil || de.IsDir() {
+				continue
+			}
+			if jp.sniffed.maker(JoinPath(fullpath, de.Name())) != nil {
+				if fi, err := de.Info(); err == nil {
+					list[i] = continfo{fi}
+				}
+			}
+		}
+	}
 	return
 }
 
@@ -122,14 +466,9 @@ func (jp *JointPool) ReadDir(fullpath string) (list []fs.DirEntry, err error) {
 // Sub implements fs.SubFS interface,
 // and returns object that can be casted to *SubPool.
 func (jp *JointPool) Sub(dir string) (fs.FS, error) {
-	var fi, err = jp.Stat(dir)
-	if err != nil {
+	if _, err := jp.Stat(dir); err != nil {
 		return nil, err
 	}
-	var jfi = fi.(FileInfo)
-	if jfi.IsRealDir() && IsTypeIso(dir) {
-		return nil, fs.ErrNotExist
-	}
 	return &SubPool{
 		JointPool: jp,
 		dir:       dir,
@@ -160,6 +499,20 @@ func (sp *SubPool) Dir() string {
 	return sp.dir
 }
 
+// fullpath returns full path at the pool for path given by
+// remote client, i.e. WebDAV or FTP client. Such path can be
+// absolute or relative, and it can not leave the root directory.
+func (sp *SubPool) fullpath(name string) (string, error) {
+	var fpath = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if fpath == "" {
+		fpath = "."
+	}
+	if !fs.ValidPath(fpath) {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return JoinPath(sp.dir, fpath), nil
+}
+
 // Open implements fs.FS interface,
 // and returns file that can be casted to joint wrapper.
 func (sp *SubPool) Open(fpath string) (f fs.File, err error) {
@@ -185,6 +538,22 @@ func (sp *SubPool) ReadDir(fpath string) (ret []fs.DirEntry, err error) {
 	return sp.JointPool.ReadDir(JoinPath(sp.dir, fpath))
 }
 
+// Lstat returns fs.FileInfo of file without following of symbolic link.
+func (sp *SubPool) Lstat(fpath string) (fi fs.FileInfo, err error) {
+	if sp.dir != "" && sp.dir != "." && !fs.ValidPath(fpath) {
+		return nil, fs.ErrInvalid
+	}
+	return sp.JointPool.Lstat(JoinPath(sp.dir, fpath))
+}
+
+// ReadLink implements fs.ReadLinkFS interface.
+func (sp *SubPool) ReadLink(fpath string) (target string, err error) {
+	if sp.dir != "" && sp.dir != "." && !fs.ValidPath(fpath) {
+		return "", fs.ErrInvalid
+	}
+	return sp.JointPool.ReadLink(JoinPath(sp.dir, fpath))
+}
+
 // Sub returns new file subsystem with given relative root directory.
 // Performs given directory check up.
 // Sub implements fs.SubFS interface,
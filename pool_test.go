@@ -1,7 +1,10 @@
 package joint_test
 
 import (
+	"errors"
 	"io/fs"
+	"os"
+	"path/filepath"
 	"testing"
 	"testing/fstest"
 
@@ -106,3 +109,162 @@ func TestPoolFS(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// Check that containers extensions are recognized without case sensitivity.
+func TestContainerCase(t *testing.T) {
+	for _, fpath := range []string{"disc.iso", "Disc.Iso", "DISC.ISO", "pack.Zip", "pack.7Z", "pack.RaR"} {
+		if !jnt.IsTypeContainer(fpath) {
+			t.Fatalf("path '%s' should be recognized as container", fpath)
+		}
+	}
+	for _, fpath := range []string{"disc.isox", "iso", "disc.txt"} {
+		if jnt.IsTypeContainer(fpath) {
+			t.Fatalf("path '%s' should not be recognized as container", fpath)
+		}
+	}
+}
+
+// Check that registered extension opens container, and
+// unregistered extension makes it regular file.
+func TestRegisterContainer(t *testing.T) {
+	var err error
+
+	var data []byte
+	if data, err = os.ReadFile("testdata/archive.zip"); err != nil {
+		t.Fatal(err)
+	}
+	jnt.MountFS("mapfs://books", fstest.MapFS{
+		"comics.CBZ": {Data: data},
+	})
+	defer jnt.UnmountFS("mapfs://books")
+
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	jnt.RegisterContainer(".cbz", func() jnt.Joint { return &jnt.ZipJoint{} })
+	var fi fs.FileInfo
+	if fi, err = jp.Stat("mapfs://books/comics.CBZ/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != foxsize {
+		t.Fatal("file size does not match")
+	}
+
+	jnt.UnregisterContainer(".CBZ")
+	if fi, err = jp.Stat("mapfs://books/comics.CBZ"); err != nil {
+		t.Fatal(err)
+	}
+	if fi.IsDir() {
+		t.Fatal("file with unregistered extension should be regular file")
+	}
+	if _, err = jp.Stat("mapfs://books/comics.CBZ/fox.txt"); err == nil {
+		t.Fatal("file inside of unregistered container should not be found")
+	}
+}
+
+// Check that real directories with containers extensions
+// are traversed as folders.
+func TestRealDirContainer(t *testing.T) {
+	var err error
+
+	var dir = t.TempDir()
+	if err = os.MkdirAll(filepath.Join(dir, "music.iso", "album.zip"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "music.iso", "album.zip", "fox.txt"),
+		[]byte("The quick brown fox jumps over the lazy dog."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var data []byte
+	if data, err = os.ReadFile("testdata/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "music.iso", "Disc.ISO"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var root = filepath.ToSlash(dir)
+
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var fi fs.FileInfo
+	if fi, err = jp.Stat(root + "/music.iso"); err != nil {
+		t.Fatal(err)
+	}
+	if !fi.(jnt.FileInfo).IsRealDir() {
+		t.Fatal("folder 'music.iso' should be recognized as real directory")
+	}
+
+	var list []fs.DirEntry
+	if list, err = jp.ReadDir(root + "/music.iso"); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 entries in folder 'music.iso', found %d", len(list))
+	}
+
+	var b []byte
+	if b, err = fs.ReadFile(jp, root+"/music.iso/album.zip/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != foxsize {
+		t.Fatal("file size does not match")
+	}
+
+	if b, err = fs.ReadFile(jp, root+"/music.iso/Disc.ISO/disk/internal.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != foxsize {
+		t.Fatal("file size does not match")
+	}
+
+	// real directory at mounted file system
+	jnt.MountFS("mapfs://music", fstest.MapFS{
+		"music.iso/fox.txt": {Data: b},
+	})
+	defer jnt.UnmountFS("mapfs://music")
+
+	if fi, err = jp.Stat("mapfs://music/music.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != foxsize {
+		t.Fatal("file size does not match")
+	}
+	if _, err = jnt.MakeJoint("mapfs://music/music.iso"); !errors.Is(err, jnt.ErrRealDir) {
+		t.Fatal("joint should not be made for real directory")
+	}
+}
+
+// Check that real directory with container extension
+// is remembered, and it's not made again.
+func TestRealDirRemembered(t *testing.T) {
+	var err error
+
+	var dir = t.TempDir()
+	if err = os.MkdirAll(filepath.Join(dir, "music.iso"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "music.iso", "fox.txt"),
+		[]byte("The quick brown fox jumps over the lazy dog."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var root = filepath.ToSlash(dir)
+
+	var faults = &jnt.Faults{}
+	var jp = jnt.NewJointPool(jnt.WithFaults(faults))
+	defer jp.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err = fs.ReadFile(jp, root+"/music.iso/fox.txt"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := faults.Calls("Make"); n != 1 {
+		t.Fatalf("real directory was made %d times", n)
+	}
+	for _, key := range jp.Keys() {
+		if key == root+"/music.iso" {
+			t.Fatal("empty cache is left for real directory")
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package joint
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	ErrResumeSize = errors.New("size of transferred file does not match to source")
+)
+
+// ResumeState is persisted state of transfer. It keeps validators of
+// transferred file to check up that file was not changed before
+// interrupted transfer is continued.
+type ResumeState struct {
+	Src     string    `json:"src"`
+	Dst     string    `json:"dst"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	ETag    string    `json:"etag,omitempty"`
+}
+
+// ResumeOptions determines how Download and Upload works.
+type ResumeOptions struct {
+	// Directory where states of transfers are stored, to continue them
+	// after process restart. "joint/resume" at user's cache directory
+	// is used if it's empty.
+	StateDir string
+	// Callback called after each written chunk of file, and after
+	// transfer completion. Written bytes includes transferred before
+	// resuming content.
+	Progress func(CopyProgress)
+}
+
+// FileETag returns entity tag of file if backend provides it,
+// i.e. for WebDAV, HTTP and S3 files, or empty string otherwise.
+func FileETag(fi fs.FileInfo) string {
+	for fi != nil {
+		if e, ok := fi.(interface{ ETag() string }); ok {
+			return e.ETag()
+		}
+		switch v := fi.(type) {
+		case fileinfo:
+			fi = v.FileInfo
+		case continfo:
+			fi = v.FileInfo
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// NewResumeState returns state of transfer with validators of given file.
+func NewResumeState(src, dst string, fi fs.FileInfo) ResumeState {
+	return ResumeState{
+		Src:     src,
+		Dst:     dst,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		ETag:    FileETag(fi),
+	}
+}
+
+// Match checks up that given state describes the same transfer
+// of the same file version.
+func (rs *ResumeState) Match(other ResumeState) bool {
+	return rs.Src == other.Src && rs.Dst == other.Dst &&
+		rs.Size == other.Size && rs.ModTime.Equal(other.ModTime) &&
+		rs.ETag == other.ETag
+}
+
+// statefile returns path to file with state of transfer.
+func (opts *ResumeOptions) statefile(src, dst string) string {
+	var dir = opts.StateDir
+	if dir == "" {
+		if cache, err := os.UserCacheDir(); err == nil {
+			dir = filepath.Join(cache, "joint", "resume")
+		} else {
+			dir = filepath.Join(os.TempDir(), "joint-resume")
+		}
+	}
+	var h = sha1.Sum([]byte(src + "\n" + dst))
+	return filepath.Join(dir, hex.EncodeToString(h[:])+".json")
+}
+
+// LoadResumeState reads persisted state of transfer.
+func LoadResumeState(fpath string) (rs ResumeState, err error) {
+	var b []byte
+	if b, err = os.ReadFile(fpath); err != nil {
+		return
+	}
+	err = json.Unmarshal(b, &rs)
+	return
+}
+
+// Save writes state of transfer to file.
+func (rs *ResumeState) Save(fpath string) (err error) {
+	var b []byte
+	if b, err = json.Marshal(rs); err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+		return
+	}
+	return os.WriteFile(fpath, b, 0644)
+}
+
+// Download copies file pointed by full path to local file. Content
+// is written to file with ".part" suffix, and it's renamed to
+// destination at the end. If transfer was interrupted, next call
+// continues it from the end of partial file, if source file has the
+// same size, modification time and entity tag as at transfer start.
+// Otherwise transfer starts from zero. Containers are copied as files.
+func (jp *JointPool) Download(ctx context.Context, src, dst string, opts ResumeOptions) (err error) {
+	var sw, spath, err1 = jp.getjoint(src, len(src)-1)
+	if err1 != nil {
+		return err1
+	}
+	defer func() { release(sw, err) }()
+	if _, err = sw.Open(spath); err != nil {
+		return
+	}
+	var fi fs.FileInfo
+	if fi, err = sw.Stat(); err != nil {
+		return
+	}
+	if isrealdir(ToDirEntry(fi)) {
+		return &fs.PathError{Op: "download", Path: src, Err: ErrCopyDir}
+	}
+
+	var part = dst + ".part"
+	var sf = opts.statefile(src, dst)
+	var rs = NewResumeState(src, dst, fi)
+	var offset int64
+	if old, err := LoadResumeState(sf); err == nil && rs.Match(old) {
+		if pfi, err := os.Stat(part); err == nil && pfi.Size() <= fi.Size() {
+			offset = pfi.Size()
+		}
+	}
+	if offset == 0 {
+		if err = rs.Save(sf); err != nil {
+			return
+		}
+	} else if _, err = sw.Seek(offset, io.SeekStart); err != nil {
+		return
+	}
+
+	var f *os.File
+	if f, err = os.OpenFile(part, os.O_WRONLY|os.O_CREATE, 0644); err != nil {
+		return
+	}
+	if err = f.Truncate(offset); err == nil {
+		if _, err = f.Seek(offset, io.SeekStart); err == nil {
+			var c = copier{jp: jp, ctx: ctx, opts: &CopyOptions{Progress: opts.Progress}}
+			c.prog = CopyProgress{Src: src, Dst: dst, Size: fi.Size(), Written: offset, Bytes: offset}
+			if _, err = io.Copy(f, copyreader{sw.Joint, &c}); err == nil {
+				c.prog.Files++
+				c.progress()
+			}
+		}
+	}
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return
+	}
+
+	var pfi fs.FileInfo
+	if pfi, err = os.Stat(part); err != nil {
+		return
+	}
+	if fi.Size() >= 0 && pfi.Size() != fi.Size() {
+		os.Remove(part)
+		os.Remove(sf)
+		return &fs.PathError{Op: "download", Path: src, Err: ErrResumeSize}
+	}
+	if err = os.Rename(part, dst); err != nil {
+		return
+	}
+	os.Remove(sf)
+	return
+}
+
+// Upload copies local file to destination pointed by full path. If
+// transfer was interrupted, next call continues it from the end of
+// destination file, if source file has the same size and modification
+// time as at transfer start, and destination joint can write file
+// from offset, i.e. at FTP, SFTP and SMB. Otherwise transfer starts
+// from zero. Destination should be at writable file system.
+func (jp *JointPool) Upload(ctx context.Context, src, dst string, opts ResumeOptions) (err error) {
+	var f *os.File
+	if f, err = os.Open(src); err != nil {
+		return
+	}
+	defer f.Close()
+	var fi fs.FileInfo
+	if fi, err = f.Stat(); err != nil {
+		return
+	}
+	if fi.IsDir() {
+		return &fs.PathError{Op: "upload", Path: src, Err: ErrCopyDir}
+	}
+
+	var sf = opts.statefile(src, dst)
+	var rs = NewResumeState(src, dst, fi)
+	var offset int64
+	if old, err := LoadResumeState(sf); err == nil && rs.Match(old) {
+		if dfi, err := jp.Lstat(dst); err == nil && dfi.Size() <= fi.Size() {
+			offset = dfi.Size()
+		}
+	}
+
+	var dw, dpath, err1 = jp.getjoint(dst, len(dst)-1)
+	if err1 != nil {
+		return err1
+	}
+	defer func() { release(dw, err) }()
+	var wj, ok = dw.Joint.(WriteJoint)
+	if !ok {
+		return &fs.PathError{Op: "upload", Path: dst, Err: ErrReadOnly}
+	}
+	var rj, canresume = dw.Joint.(ResumeJoint)
+	if !canresume {
+		offset = 0
+	}
+	if offset == 0 {
+		if err = rs.Save(sf); err != nil {
+			return
+		}
+	} else if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return
+	}
+
+	var c = copier{jp: jp, ctx: ctx, opts: &CopyOptions{Progress: opts.Progress}}
+	c.prog = CopyProgress{Src: src, Dst: dst, Size: fi.Size(), Written: offset, Bytes: offset}
+	if offset > 0 {
+		err = rj.PutFrom(dpath, copyreader{f, &c}, offset)
+	} else {
+		err = wj.Put(dpath, copyreader{f, &c})
+	}
+	if err != nil {
+		return
+	}
+	c.prog.Files++
+	c.progress()
+	os.Remove(sf)
+	return
+}
@@ -0,0 +1,171 @@
+package joint_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// transfer runs given resumable transfer, and cancels it after
+// the first written chunk if break is set. Returns number of bytes
+// at first reported progress.
+func transfer(t *testing.T, fn func(ctx context.Context, opts jnt.ResumeOptions) error, statedir string, brk bool) (first int64) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	first = -1
+	var opts = jnt.ResumeOptions{
+		StateDir: statedir,
+		Progress: func(p jnt.CopyProgress) {
+			if first < 0 {
+				first = p.Written
+			}
+			if brk {
+				cancel()
+			}
+		},
+	}
+	var err = fn(ctx, opts)
+	if brk {
+		if !errors.Is(err, context.Canceled) {
+			t.Fatal("transfer should be broken by context")
+		}
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestDownload(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+	var root = t.TempDir()
+	var statedir = filepath.Join(root, "state")
+	var src = "testdata/external.iso"
+	var dst = filepath.Join(root, "external.iso")
+	var download = func(ctx context.Context, opts jnt.ResumeOptions) error {
+		return jp.Download(ctx, src, dst, opts)
+	}
+
+	var orig, err = os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := transfer(t, download, statedir, true); first <= 0 {
+		t.Fatal("first chunk is not reported")
+	}
+	var fi os.FileInfo
+	if fi, err = os.Stat(dst + ".part"); err != nil {
+		t.Fatal(err)
+	}
+	var partsize = fi.Size()
+	if partsize == 0 || partsize == int64(len(orig)) {
+		t.Fatal("partial file size does not match")
+	}
+	if first := transfer(t, download, statedir, false); first <= partsize {
+		t.Fatal("transfer should be continued from the end of partial file")
+	}
+	var b []byte
+	if b, err = os.ReadFile(dst); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, orig) {
+		t.Fatal("content of downloaded file does not match")
+	}
+	if _, err = os.Stat(dst + ".part"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("partial file should be removed")
+	}
+
+	// file inside of container
+	src, dst = "testdata/external.iso/disk/internal.iso/fox.txt", filepath.Join(root, "fox.txt")
+	transfer(t, download, statedir, false)
+	if b, err = os.ReadFile(dst); err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != foxsize {
+		t.Fatal("size of downloaded file does not match")
+	}
+}
+
+func TestDownloadChanged(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+	var root = t.TempDir()
+	var statedir = filepath.Join(root, "state")
+	var src = filepath.ToSlash(filepath.Join(root, "source.bin"))
+	var dst = filepath.Join(root, "target.bin")
+	var download = func(ctx context.Context, opts jnt.ResumeOptions) error {
+		return jp.Download(ctx, src, dst, opts)
+	}
+
+	var data = bytes.Repeat([]byte("The quick brown fox jumps over the lazy dog. "), 10000)
+	if err := os.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	transfer(t, download, statedir, true)
+
+	// source is changed, so transfer should be restarted
+	data = bytes.ToUpper(data)
+	if err := os.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var mtime = time.Now().Add(time.Hour)
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if first := transfer(t, download, statedir, false); first > 64*1024 {
+		t.Fatal("transfer of changed file should be restarted")
+	}
+	var b, err = os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, data) {
+		t.Fatal("content of downloaded file does not match")
+	}
+}
+
+func TestUpload(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+	var root = t.TempDir()
+	var statedir = filepath.Join(root, "state")
+	var src = "testdata/external.iso"
+	var dst = filepath.ToSlash(filepath.Join(root, "external.iso"))
+	var upload = func(ctx context.Context, opts jnt.ResumeOptions) error {
+		return jp.Upload(ctx, src, dst, opts)
+	}
+
+	var orig, err = os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer(t, upload, statedir, true)
+	var fi os.FileInfo
+	if fi, err = os.Stat(dst); err != nil {
+		t.Fatal(err)
+	}
+	var partsize = fi.Size()
+	if partsize == 0 || partsize == int64(len(orig)) {
+		t.Fatal("partial file size does not match")
+	}
+	if first := transfer(t, upload, statedir, false); first <= partsize {
+		t.Fatal("transfer should be continued from the end of partial file")
+	}
+	var b []byte
+	if b, err = os.ReadFile(dst); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, orig) {
+		t.Fatal("content of uploaded file does not match")
+	}
+
+	if err = jp.Upload(context.Background(), src, dst+"/fox.txt", jnt.ResumeOptions{StateDir: statedir}); !errors.Is(err, jnt.ErrReadOnly) {
+		t.Fatal("container should not be writable")
+	}
+}
@@ -0,0 +1,356 @@
+package joint_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// fakes3 is minimal in-memory S3-compatible service for tests.
+// It does not verify request signatures.
+type fakes3 struct {
+	buckets map[string]map[string][]byte
+	uploads map[string]map[int][]byte
+	mux     sync.Mutex
+}
+
+// newFakeS3 starts fake S3-service with "testdata" bucket
+// that contains external ISO-image.
+func newFakeS3(t *testing.T) *httptest.Server {
+	var data, err = os.ReadFile("testdata/external.iso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s = &fakes3{
+		buckets: map[string]map[string][]byte{
+			"testdata": {"external.iso": data},
+		},
+		uploads: map[string]map[int][]byte{},
+	}
+	return httptest.NewServer(s)
+}
+
+var s3time = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func (s *fakes3) fail(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+func (s *fakes3) body(r *http.Request) ([]byte, error) {
+	var b, err = io.ReadAll(r.Body)
+	if err != nil || !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return b, err
+	}
+	// decode aws-chunked content
+	var buf bytes.Buffer
+	var rd = bufio.NewReader(bytes.NewReader(b))
+	for {
+		var line, err = rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		var hex, _, _ = strings.Cut(strings.TrimSpace(line), ";")
+		var size, _ = strconv.ParseInt(hex, 16, 64)
+		if size == 0 {
+			return buf.Bytes(), nil
+		}
+		if _, err = io.CopyN(&buf, rd, size); err != nil {
+			return nil, err
+		}
+		rd.ReadString('\n')
+	}
+}
+
+func (s *fakes3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	var q = r.URL.Query()
+	var bucket, key, _ = strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket == "" {
+		var names []string
+		for name := range s.buckets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprint(w, `<ListAllMyBucketsResult><Owner><ID>test</ID></Owner><Buckets>`)
+		for _, name := range names {
+			fmt.Fprintf(w, `<Bucket><Name>%s</Name><CreationDate>%s</CreationDate></Bucket>`,
+				name, s3time.Format(time.RFC3339))
+		}
+		fmt.Fprint(w, `</Buckets></ListAllMyBucketsResult>`)
+		return
+	}
+	var objects, ok = s.buckets[bucket]
+	if !ok {
+		s.fail(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	if key == "" {
+		if q.Has("location") {
+			fmt.Fprint(w, `<LocationConstraint></LocationConstraint>`)
+			return
+		}
+		if r.Method == http.MethodHead {
+			return
+		}
+		var prefix, delim = q.Get("prefix"), q.Get("delimiter")
+		var keys []string
+		for k := range objects {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var dirs = map[string]bool{}
+		fmt.Fprintf(w, `<ListBucketResult><Name>%s</Name><Prefix>%s</Prefix><IsTruncated>false</IsTruncated>`, bucket, prefix)
+		for _, k := range keys {
+			if !strings.HasPrefix(k, prefix) {
+				continue
+			}
+			var rest = k[len(prefix):]
+			if i := strings.Index(rest, delim); delim != "" && i != -1 {
+				var dir = prefix + rest[:i+1]
+				if !dirs[dir] {
+					dirs[dir] = true
+					fmt.Fprintf(w, `<CommonPrefixes><Prefix>%s</Prefix></CommonPrefixes>`, xmlesc(dir))
+				}
+				continue
+			}
+			fmt.Fprintf(w, `<Contents><Key>%s</Key><LastModified>%s</LastModified><ETag>"%x"</ETag><Size>%d</Size></Contents>`,
+				xmlesc(k), s3time.Format(time.RFC3339), len(objects[k]), len(objects[k]))
+		}
+		fmt.Fprint(w, `</ListBucketResult>`)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		var data, ok = objects[key]
+		if !ok {
+			s.fail(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, len(data)))
+		http.ServeContent(w, r, key, s3time, bytes.NewReader(data))
+	case http.MethodPut:
+		var data, err = s.body(r)
+		if err != nil {
+			s.fail(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		if q.Has("partNumber") {
+			var num, _ = strconv.Atoi(q.Get("partNumber"))
+			s.uploads[q.Get("uploadId")][num] = data
+		} else {
+			objects[key] = data
+		}
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, len(data)))
+	case http.MethodPost:
+		if q.Has("uploads") {
+			var id = strconv.Itoa(len(s.uploads) + 1)
+			s.uploads[id] = map[int][]byte{}
+			fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`,
+				bucket, xmlesc(key), id)
+			return
+		}
+		var parts = s.uploads[q.Get("uploadId")]
+		var nums []int
+		for num := range parts {
+			nums = append(nums, num)
+		}
+		sort.Ints(nums)
+		var data []byte
+		for _, num := range nums {
+			data = append(data, parts[num]...)
+		}
+		objects[key] = data
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><ETag>"%x"</ETag></CompleteMultipartUploadResult>`,
+			bucket, xmlesc(key), len(data))
+	case http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.fail(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func xmlesc(s string) string {
+	var buf strings.Builder
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// s3addr returns address of fake S3-service with credentials.
+func s3addr(srv *httptest.Server) string {
+	return "s3+http://key:secret@" + strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestS3Joint(t *testing.T) {
+	var err error
+
+	var srv = newFakeS3(t)
+	defer srv.Close()
+
+	var j = &jnt.S3Joint{}
+	if err = j.Make(nil, s3addr(srv)); err != nil {
+		t.Fatal(err)
+	}
+	defer j.Cleanup()
+
+	var fi fs.FileInfo
+	if fi, err = j.Info("testdata/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+
+	if fi.Size() != isosize {
+		t.Fatal("ISO-file size does not match")
+	}
+
+	if err = checkReadDir(j); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Check directory list in external ISO-disk placed at S3.
+func TestS3ExtDirList(t *testing.T) {
+	var err error
+
+	var srv = newFakeS3(t)
+	defer srv.Close()
+
+	var j1 jnt.Joint = &jnt.S3Joint{}
+	if err = j1.Make(nil, s3addr(srv)); err != nil {
+		t.Fatal(err)
+	}
+
+	var j2 jnt.Joint = &jnt.IsoJoint{}
+	if err = j2.Make(j1, "testdata/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+	defer j2.Cleanup()
+
+	for fpath := range extdirs {
+		if err = checkDir(j2, fpath, extdirs); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Read files chunks on S3Joint.
+func TestS3ReadChunk(t *testing.T) {
+	var err error
+
+	var srv = newFakeS3(t)
+	defer srv.Close()
+
+	var j1 jnt.Joint = &jnt.S3Joint{}
+	if err = j1.Make(nil, s3addr(srv)); err != nil {
+		t.Fatal(err)
+	}
+	defer j1.Cleanup()
+
+	var j2 jnt.Joint = &jnt.IsoJoint{}
+	if err = readChunk(j2, j1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Check that objects can be written and nested ISO-disks
+// can be opened by pool.
+func TestS3PoolWrite(t *testing.T) {
+	var err error
+
+	var srv = newFakeS3(t)
+	defer srv.Close()
+
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var f fs.File
+	if f, err = jp.Open(s3addr(srv) + "/testdata/external.iso/disk/internal.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	var content []byte
+	content, err = io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f, err = jp.Open(s3addr(srv) + "/testdata/copy/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.(jnt.JointWrap).Joint.(io.Writer).Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var fi fs.FileInfo
+	if fi, err = jp.Stat(s3addr(srv) + "/testdata/copy"); err != nil {
+		t.Fatal(err)
+	}
+	if !fi.IsDir() {
+		t.Fatal("path 'copy' should be recognized as directory")
+	}
+	if fi, err = jp.Stat(s3addr(srv) + "/testdata/copy/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != foxsize {
+		t.Fatal("size of written file does not match")
+	}
+}
+
+func TestS3Remove(t *testing.T) {
+	var srv = newFakeS3(t)
+	defer srv.Close()
+
+	var j = &jnt.S3Joint{}
+	if err := j.Make(nil, s3addr(srv)); err != nil {
+		t.Fatal(err)
+	}
+	defer j.Cleanup()
+
+	if _, err := j.Info("nobucket/fox.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("file at absent bucket should not exist, got %v", err)
+	}
+	if err := j.Mkdir("testdata/dir"); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Put("testdata/dir/fox.txt", strings.NewReader("fox")); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Remove("testdata/dir"); !errors.Is(err, syscall.ENOTEMPTY) {
+		t.Fatalf("directory with objects should not be removed, got %v", err)
+	}
+	if _, err := j.Info("testdata/dir/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Remove("testdata/dir/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Remove("testdata/dir"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Info("testdata/dir"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("directory should be removed, got %v", err)
+	}
+}
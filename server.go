@@ -0,0 +1,140 @@
+package joint
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	ErrAuth      = errors.New("user name or password is wrong")
+	ErrSrvClosed = errors.New("server is closed")
+)
+
+// ServerUser is account of FTP or SFTP server.
+type ServerUser struct {
+	// Password of user. Empty password allows any password,
+	// that is usual for anonymous account.
+	Password string
+	// Full path of root directory of user at the pool. User can not
+	// leave it, and see it as root directory of server.
+	Root string
+	// User can create, rewrite, rename and remove files at writable
+	// joints. Otherwise all modifications are rejected.
+	Writable bool
+}
+
+// ServerUsers is accounts of FTP or SFTP server by user names.
+type ServerUsers map[string]ServerUser
+
+// Login checks user name and password, and returns account of user
+// and file subsystem with root directory of user at given pool.
+func (us ServerUsers) Login(jp *JointPool, name, pass string) (sp *SubPool, user ServerUser, err error) {
+	var ok bool
+	if user, ok = us[name]; !ok {
+		err = ErrAuth
+		return
+	}
+	if user.Password != "" && subtle.ConstantTimeCompare([]byte(user.Password), []byte(pass)) != 1 {
+		err = ErrAuth
+		return
+	}
+	sp = NewSubPool(jp, user.Root)
+	return
+}
+
+// srvconns keeps opened connections of server to close them
+// at server closing.
+type srvconns struct {
+	conns map[io.Closer]struct{}
+	wg    sync.WaitGroup
+	mux   sync.Mutex
+	done  bool
+}
+
+// add registers new connection, returns false if server is closed.
+func (sc *srvconns) add(c io.Closer) bool {
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+	if sc.done {
+		return false
+	}
+	if sc.conns == nil {
+		sc.conns = map[io.Closer]struct{}{}
+	}
+	sc.conns[c] = struct{}{}
+	sc.wg.Add(1)
+	return true
+}
+
+// remove unregisters connection.
+func (sc *srvconns) remove(c io.Closer) {
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+	if _, ok := sc.conns[c]; ok {
+		delete(sc.conns, c)
+		sc.wg.Done()
+	}
+}
+
+// closed returns true if server is closed.
+func (sc *srvconns) closed() bool {
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+	return sc.done
+}
+
+// close closes all connections and waits until they are handled.
+func (sc *srvconns) close() (err error) {
+	sc.mux.Lock()
+	sc.done = true
+	var errs []error
+	for c := range sc.conns {
+		errs = append(errs, c.Close())
+	}
+	sc.mux.Unlock()
+	sc.wg.Wait()
+	return errors.Join(errs...)
+}
+
+// serve accepts connections at listener and handles them
+// in separate goroutines until listener is closed.
+func (sc *srvconns) serve(l net.Listener, handle func(c net.Conn)) error {
+	if !sc.add(l) {
+		return ErrSrvClosed
+	}
+	defer sc.remove(l)
+	var delay time.Duration
+	for {
+		var c, err = l.Accept()
+		if err != nil {
+			if sc.closed() {
+				return ErrSrvClosed
+			}
+			var ne interface{ Temporary() bool }
+			if errors.As(err, &ne) && ne.Temporary() {
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > time.Second {
+					delay = time.Second
+				}
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+		if !sc.add(c) {
+			c.Close()
+			return ErrSrvClosed
+		}
+		go func() {
+			defer sc.remove(c)
+			defer c.Close()
+			handle(c)
+		}()
+	}
+}
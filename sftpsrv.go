@@ -0,0 +1,321 @@
+package joint
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// SftpHandlers returns handlers for sftp.RequestServer that shares
+// content of given subsystem. Containers are represented as
+// directories. Files can be created, moved and removed if access is
+// writable, and they are placed at writable joints. Written files are
+// always rewritten from the beginning, append mode is not supported.
+func SftpHandlers(sp *SubPool, writable bool) sftp.Handlers {
+	var h = &sftphandler{sp: sp, writable: writable}
+	return sftp.Handlers{
+		FileGet:  h,
+		FilePut:  h,
+		FileCmd:  h,
+		FileList: h,
+	}
+}
+
+// sftperr converts error to SFTP status error.
+func sftperr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, fs.ErrNotExist):
+		return sftp.ErrSSHFxNoSuchFile
+	case errors.Is(err, fs.ErrPermission), errors.Is(err, ErrReadOnly):
+		return sftp.ErrSSHFxPermissionDenied
+	case errors.Is(err, errors.ErrUnsupported):
+		return sftp.ErrSSHFxOpUnsupported
+	}
+	return err
+}
+
+// sftphandler implements handlers of SFTP requests over SubPool.
+type sftphandler struct {
+	sp       *SubPool
+	writable bool
+}
+
+// Fileread implements sftp.FileReader interface.
+func (h *sftphandler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	var fullpath, err = h.sp.fullpath(r.Filepath)
+	if err != nil {
+		return nil, sftperr(err)
+	}
+	var fi fs.FileInfo
+	if fi, err = h.sp.JointPool.Stat(fullpath); err != nil {
+		return nil, sftperr(err)
+	}
+	if isrealdir(ToDirEntry(fi)) {
+		return nil, sftp.ErrSSHFxFailure
+	}
+	// containers are read as files
+	var jw, fpath, err1 = h.sp.JointPool.getjoint(fullpath, len(fullpath)-1)
+	if err1 != nil {
+		return nil, sftperr(err1)
+	}
+	if _, err = jw.Open(fpath); err != nil {
+		release(jw, err)
+		return nil, sftperr(err)
+	}
+	return &jointreader{jw: jw}, nil
+}
+
+// Filewrite implements sftp.FileWriter interface.
+func (h *sftphandler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	if !h.writable {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	if r.Pflags().Append {
+		return nil, sftp.ErrSSHFxOpUnsupported
+	}
+	var fullpath, err = h.sp.fullpath(r.Filepath)
+	if err != nil {
+		return nil, sftperr(err)
+	}
+	var fi fs.FileInfo
+	if fi, err = h.sp.JointPool.Lstat(fullpath); err == nil {
+		if isrealdir(ToDirEntry(fi)) {
+			return nil, sftp.ErrSSHFxFailure
+		}
+		if !r.Pflags().Trunc { // content can be only rewritten entirely
+			return nil, sftp.ErrSSHFxOpUnsupported
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, sftperr(err)
+	}
+	var wc io.WriteCloser
+	if wc, err = h.sp.JointPool.Create(fullpath); err != nil {
+		return nil, sftperr(err)
+	}
+	return &seqwriter{wc: wc}, nil
+}
+
+// Filecmd implements sftp.FileCmder interface.
+func (h *sftphandler) Filecmd(r *sftp.Request) (err error) {
+	if !h.writable {
+		return sftp.ErrSSHFxPermissionDenied
+	}
+	var fullpath string
+	if fullpath, err = h.sp.fullpath(r.Filepath); err != nil {
+		return sftperr(err)
+	}
+	switch r.Method {
+	case "Setstat":
+		// only modification time can be changed,
+		// other attributes are ignored
+		if r.AttrFlags().Acmodtime {
+			var mtime = time.Unix(int64(r.Attributes().Mtime), 0)
+			if err = h.sp.JointPool.Chtimes(fullpath, mtime); errors.Is(err, errors.ErrUnsupported) {
+				err = nil
+			}
+		}
+	case "Rename":
+		var target string
+		if target, err = h.sp.fullpath(r.Target); err != nil {
+			return sftperr(err)
+		}
+		err = h.sp.JointPool.Rename(fullpath, target)
+	case "Rmdir", "Remove":
+		err = h.sp.JointPool.Remove(fullpath)
+	case "Mkdir":
+		err = h.sp.JointPool.Mkdir(fullpath)
+	default:
+		err = sftp.ErrSSHFxOpUnsupported
+	}
+	return sftperr(err)
+}
+
+// Filelist implements sftp.FileLister interface.
+func (h *sftphandler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	var fullpath, err = h.sp.fullpath(r.Filepath)
+	if err != nil {
+		return nil, sftperr(err)
+	}
+	switch r.Method {
+	case "List":
+		var list []fs.DirEntry
+		if list, err = h.sp.JointPool.ReadDir(fullpath); err != nil {
+			return nil, sftperr(err)
+		}
+		var ls = make(sftplist, 0, len(list))
+		for _, de := range list {
+			if fi, err := de.Info(); err == nil {
+				ls = append(ls, fi)
+			}
+		}
+		return ls, nil
+	case "Stat":
+		var fi fs.FileInfo
+		if fi, err = h.sp.JointPool.Stat(fullpath); err != nil {
+			return nil, sftperr(err)
+		}
+		return sftplist{fi}, nil
+	case "Readlink":
+		var target string
+		if target, err = h.sp.JointPool.ReadLink(fullpath); err != nil {
+			return nil, sftperr(err)
+		}
+		return sftplist{linkinfo(target)}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+// Lstat implements sftp.LstatFileLister interface.
+func (h *sftphandler) Lstat(r *sftp.Request) (sftp.ListerAt, error) {
+	var fullpath, err = h.sp.fullpath(r.Filepath)
+	if err != nil {
+		return nil, sftperr(err)
+	}
+	var fi fs.FileInfo
+	if fi, err = h.sp.JointPool.Lstat(fullpath); err != nil {
+		return nil, sftperr(err)
+	}
+	return sftplist{fi}, nil
+}
+
+// sftplist implements sftp.ListerAt interface.
+type sftplist []fs.FileInfo
+
+func (l sftplist) ListAt(ls []fs.FileInfo, offset int64) (n int, err error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	if n = copy(ls, l[offset:]); n < len(ls) {
+		err = io.EOF
+	}
+	return
+}
+
+// linkinfo is file info that passes target of symbolic link as its name.
+type linkinfo string
+
+func (fi linkinfo) Name() string       { return string(fi) }
+func (fi linkinfo) Size() int64        { return 0 }
+func (fi linkinfo) Mode() fs.FileMode  { return fs.ModeSymlink | 0777 }
+func (fi linkinfo) ModTime() time.Time { return time.Time{} }
+func (fi linkinfo) IsDir() bool        { return false }
+func (fi linkinfo) Sys() interface{}   { return nil }
+
+// SftpServer is SSH-server with SFTP subsystem that shares content
+// of the pool. Each user has own root directory at the pool, and
+// read-only or read-write access to it. Account of user is taken
+// by name of SSH-connection, so if any authentication methods are
+// added to config, authenticated users should be present in Users.
+type SftpServer struct {
+	// Accounts of server users.
+	Users ServerUsers
+	// Configuration of SSH-server, it has host key and password
+	// authentication by Users accounts.
+	Config *ssh.ServerConfig
+
+	jp *JointPool
+	sc srvconns
+}
+
+// NewSftpServer returns SFTP-server with given pool,
+// users accounts and host key.
+func NewSftpServer(jp *JointPool, users ServerUsers, key ssh.Signer) *SftpServer {
+	if jp == nil {
+		jp = NewJointPool()
+	}
+	var s = &SftpServer{
+		Users: users,
+		jp:    jp,
+	}
+	s.Config = &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			var log = s.jp.logger().With("protocol", "sftp", "remote", c.RemoteAddr().String())
+			if _, _, err := s.Users.Login(s.jp, c.User(), string(pass)); err != nil {
+				log.Warn("login failed", "user", c.User(), "err", err)
+				return nil, err
+			}
+			log.Info("user logged in", "user", c.User())
+			return nil, nil
+		},
+	}
+	s.Config.AddHostKey(key)
+	return s
+}
+
+// Serve accepts incoming connections on the listener, and serves
+// each connection in new goroutine. Always returns non-nil error,
+// ErrSrvClosed after Close call.
+func (s *SftpServer) Serve(l net.Listener) error {
+	return s.sc.serve(l, s.handle)
+}
+
+// ListenAndServe listens on the TCP network address and then
+// calls Serve to handle incoming connections.
+func (s *SftpServer) ListenAndServe(addr string) error {
+	var l, err = net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Close closes all listeners and active connections.
+func (s *SftpServer) Close() error {
+	return s.sc.close()
+}
+
+// handle serves SSH-connection.
+func (s *SftpServer) handle(conn net.Conn) {
+	var sconn, chans, reqs, err = ssh.NewServerConn(conn, s.Config)
+	if err != nil {
+		return
+	}
+	defer sconn.Close()
+	go ssh.DiscardRequests(reqs)
+
+	var user, ok = s.Users[sconn.User()]
+	if !ok {
+		return
+	}
+	var sp = NewSubPool(s.jp, user.Root)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for newch := range chans {
+		if newch.ChannelType() != "session" {
+			newch.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		var ch, chreqs, err = newch.Accept()
+		if err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer ch.Close()
+			for req := range chreqs {
+				// payload of subsystem request is SSH-string with name
+				var ok = req.Type == "subsystem" && len(req.Payload) >= 4 &&
+					string(req.Payload[4:]) == "sftp" && binary.BigEndian.Uint32(req.Payload) == 4
+				req.Reply(ok, nil)
+				if ok {
+					go func() {
+						var srv = sftp.NewRequestServer(ch, SftpHandlers(sp, user.Writable))
+						srv.Serve()
+						srv.Close()
+						ch.Close()
+					}()
+				}
+			}
+		}()
+	}
+}
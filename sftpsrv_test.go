@@ -0,0 +1,180 @@
+package joint_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+	jnt "github.com/schwarzlichtbezirk/joint"
+	"golang.org/x/crypto/ssh"
+)
+
+// startSftp starts SFTP-server at local host with read-only user
+// "reader" at testdata directory, and read-write user "writer"
+// at given directory. Returns address of server.
+func startSftp(t *testing.T, root string) string {
+	var _, pk, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var key ssh.Signer
+	if key, err = ssh.NewSignerFromKey(pk); err != nil {
+		t.Fatal(err)
+	}
+	var l net.Listener
+	if l, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	var cwd, _ = os.Getwd()
+	var srv = jnt.NewSftpServer(nil, jnt.ServerUsers{
+		"reader": {Password: "pass", Root: filepath.ToSlash(filepath.Join(cwd, "testdata"))},
+		"writer": {Password: "pass", Root: root, Writable: true},
+	}, key)
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return l.Addr().String()
+}
+
+// dialSftp returns SFTP-client connected with given user.
+func dialSftp(t *testing.T, addr, user string) *sftp.Client {
+	var conn, err = ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.Password("pass")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var client *sftp.Client
+	if client, err = sftp.NewClient(conn); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		conn.Close()
+	})
+	return client
+}
+
+func TestSftpServer(t *testing.T) {
+	var addr = startSftp(t, filepath.ToSlash(t.TempDir()))
+	var client = dialSftp(t, addr, "reader")
+
+	var list, err = client.ReadDir("/external.iso/data/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("number of files does not match: %v", list)
+	}
+	var fi fs.FileInfo
+	if fi, err = client.Stat("/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+	if !fi.IsDir() {
+		t.Fatal("container should be represented as directory")
+	}
+	var f *sftp.File
+	if f, err = client.Open("/external.iso/disk/internal.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	var b []byte
+	b, err = io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != foxsize {
+		t.Fatal("size of file does not match")
+	}
+	if _, err = client.Stat("/../external.iso"); err != nil {
+		t.Fatal(err) // path is resolved inside of root
+	}
+	if err = client.Mkdir("/dir"); !errors.Is(err, fs.ErrPermission) {
+		t.Fatal("read-only user should not create directory")
+	}
+}
+
+func TestSftpServerWrite(t *testing.T) {
+	var root = filepath.ToSlash(t.TempDir())
+	var addr = startSftp(t, root)
+	var client = dialSftp(t, addr, "writer")
+
+	var orig, err = os.ReadFile("testdata/external.iso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Mkdir("/dir"); err != nil {
+		t.Fatal(err)
+	}
+	var f *sftp.File
+	if f, err = client.Create("/dir/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.ReadFrom(bytes.NewReader(orig)); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var b []byte
+	if b, err = os.ReadFile(root + "/dir/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, orig) {
+		t.Fatal("content of uploaded file does not match")
+	}
+	if err = client.Rename("/dir/external.iso", "/external.iso"); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Remove("/dir"); err != nil {
+		t.Fatal(err)
+	}
+	var list []fs.FileInfo
+	if list, err = client.ReadDir("/external.iso/data/docs"); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("number of files does not match: %v", list)
+	}
+}
+
+func TestSftpServerWriteLimits(t *testing.T) {
+	var root = filepath.ToSlash(t.TempDir())
+	var addr = startSftp(t, root)
+	var client = dialSftp(t, addr, "writer")
+
+	if err := os.WriteFile(root+"/fox.txt", []byte("The quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// existing file can not be changed without truncation
+	if f, err := client.OpenFile("/fox.txt", os.O_WRONLY); err == nil {
+		f.Close()
+		t.Fatal("existing file should not be opened for writing without truncation")
+	}
+	var b, err = os.ReadFile(root + "/fox.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "The quick brown fox" {
+		t.Fatal("content of existing file is changed")
+	}
+
+	// chunks received out of order are limited
+	var f *sftp.File
+	if f, err = client.Create("/gap.bin"); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err = f.WriteAt(make([]byte, jnt.SeqPendingLimit+1), 1); err == nil {
+		t.Fatal("chunks beyond the limit should not be kept")
+	}
+}
@@ -0,0 +1,189 @@
+package joint
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sync"
+)
+
+// SniffSize is the size of file beginning that is read
+// to recognize container by its content. It covers ISO9660
+// primary volume descriptor at 16th sector.
+const SniffSize = 0x8800
+
+// Sniffer recognizes container by content of file beginning, and
+// returns joint maker for it, or nil if content is not recognized.
+// Given buffer can be shorter than SniffSize for small files.
+type Sniffer func(head []byte) JointMaker
+
+// sniffers is global list of containers recognizers.
+var sniffers = []Sniffer{
+	SniffIso,
+	SniffArchive,
+	SniffDiskImage,
+}
+var sniffersmux sync.RWMutex
+
+// RegisterSniffer adds recognizer of container content to the end
+// of recognizers list. Recognizers are called in order of registration.
+func RegisterSniffer(s Sniffer) {
+	sniffersmux.Lock()
+	sniffers = append(sniffers, s)
+	sniffersmux.Unlock()
+}
+
+// SniffIso recognizes ISO9660 image by volume descriptor signature.
+func SniffIso(head []byte) JointMaker {
+	if len(head) >= 0x8006 && string(head[0x8001:0x8006]) == "CD001" {
+		return func() Joint { return &IsoJoint{} }
+	}
+	return nil
+}
+
+// SniffArchive recognizes ZIP, 7z, RAR and tar archives by their signatures.
+func SniffArchive(head []byte) JointMaker {
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return func() Joint { return &ZipJoint{} }
+	case bytes.HasPrefix(head, []byte("7z\xbc\xaf\x27\x1c")):
+		return func() Joint { return &SevenZipJoint{} }
+	case bytes.HasPrefix(head, []byte("Rar!\x1a\x07\x00")), bytes.HasPrefix(head, []byte("Rar!\x1a\x07\x01\x00")):
+		return func() Joint { return &RarJoint{} }
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return func() Joint { return &TarJoint{} }
+	}
+	return nil
+}
+
+// SniffDiskImage recognizes disk image with ext2/3/4 or FAT file system,
+// or with MBR partitions table.
+func SniffDiskImage(head []byte) JointMaker {
+	var le = binary.LittleEndian
+	if len(head) >= 2048 && IsExt(head[1024:]) &&
+		le.Uint32(head[1024:]) != 0 && // inodes count
+		le.Uint32(head[1024+24:]) <= 6 && // log block size
+		le.Uint32(head[1024+32:]) != 0 { // blocks per group
+		return func() Joint { return &ImgJoint{} }
+	}
+	if IsFat(head) {
+		return func() Joint { return &ImgJoint{} }
+	}
+	if len(head) < imgSector || head[510] != 0x55 || head[511] != 0xaa {
+		return nil
+	}
+	var found bool
+	for i := 0; i < 4; i++ {
+		var ent = head[446+i*16:]
+		if ent[0] != 0x00 && ent[0] != 0x80 { // boot flag
+			return nil
+		}
+		if ent[4] != 0x00 { // partition type
+			if le.Uint32(ent[12:]) == 0 { // length
+				return nil
+			}
+			found = true
+		}
+	}
+	if found {
+		return func() Joint { return &ImgJoint{} }
+	}
+	return nil
+}
+
+// SniffContainer reads beginning of given file and returns joint
+// maker for recognized container, or nil.
+func SniffContainer(r io.ReaderAt) (JointMaker, error) {
+	var head = make([]byte, SniffSize)
+	var n, err = r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	sniffersmux.RLock()
+	defer sniffersmux.RUnlock()
+	for _, s := range sniffers {
+		if maker := s(head); maker != nil {
+			return maker, nil
+		}
+	}
+	return nil, nil
+}
+
+// sniffcache keeps results of content recognition by full paths of
+// files without passwords. Nil maker means regular file. Number of
+// results is limited by Cfg.SniffCacheSize, and the oldest results
+// are removed first.
+type sniffcache struct {
+	m    map[string]JointMaker
+	keys []string // ring of keys in order of addition
+	pos  int      // position of the oldest key at the ring
+	mux  sync.RWMutex
+}
+
+// maker returns joint maker for the file at given full path,
+// which was recognized as container by its content. Returns nil if
+// content recognition is disabled by configuration.
+func (sc *sniffcache) maker(fullpath string) JointMaker {
+	if sc == nil || !Cfg.SniffContent {
+		return nil
+	}
+	sc.mux.RLock()
+	defer sc.mux.RUnlock()
+	return sc.m[Redact(fullpath)]
+}
+
+// has returns true if file at given full path was already
+// inspected by content recognition.
+func (sc *sniffcache) has(fullpath string) (ok bool) {
+	sc.mux.RLock()
+	_, ok = sc.m[Redact(fullpath)]
+	sc.mux.RUnlock()
+	return
+}
+
+// set stores result of content recognition for the file
+// at given full path.
+func (sc *sniffcache) set(fullpath string, maker JointMaker) {
+	var key = Redact(fullpath)
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+	if sc.m == nil {
+		sc.m = map[string]JointMaker{}
+	}
+	if _, ok := sc.m[key]; !ok {
+		if Cfg.SniffCacheSize <= 0 {
+			return
+		}
+		if len(sc.keys) < Cfg.SniffCacheSize {
+			sc.keys = append(sc.keys, key)
+		} else {
+			delete(sc.m, sc.keys[sc.pos])
+			sc.keys[sc.pos] = key
+			sc.pos = (sc.pos + 1) % len(sc.keys)
+		}
+	}
+	sc.m[key] = maker
+}
+
+// clear removes all results.
+func (sc *sniffcache) clear() {
+	sc.mux.Lock()
+	clear(sc.m)
+	sc.keys, sc.pos = nil, 0
+	sc.mux.Unlock()
+}
+
+// SniffedMaker returns joint maker for the file at given full path,
+// which was recognized as container by its content at this pool.
+// Returns nil if content recognition is disabled by configuration.
+func (jp *JointPool) SniffedMaker(fullpath string) JointMaker {
+	return jp.sniffed.maker(fullpath)
+}
+
+// ClearSniffed removes all results of content recognition,
+// so files will be inspected again on next access.
+func (jp *JointPool) ClearSniffed() {
+	jp.sniffed.clear()
+}
@@ -0,0 +1,214 @@
+package joint_test
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// Containers copied to files without extensions.
+var sniffcopy = map[string]string{
+	"disk1":   "testdata/external.iso",
+	"archive": "testdata/archive.zip",
+	"solid":   "testdata/solid.7z",
+	"rar":     "testdata/archive.rar",
+	"backup":  "testdata/archive.tar",
+	"drive":   "testdata/disk.img",
+}
+
+// Files to read through containers recognized by content.
+var snifffiles = map[string]int{
+	"disk1/fox.txt":                   foxsize,
+	"disk1/disk/internal.iso/fox.txt": foxsize,
+	"archive/fox.txt":                 foxsize,
+	"solid/data/docs/doc1.txt":        445,
+	"rar/fox.txt":                     foxsize,
+	"backup/data/доки/док2.txt":       1429,
+	"drive/p2/data/docs/doc1.txt":     445,
+}
+
+func makeSniffDir(t *testing.T) string {
+	var root = filepath.ToSlash(t.TempDir())
+	for name, src := range sniffcopy {
+		var b, err = os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(filepath.Join(root, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// regular file without extension
+	if err := os.WriteFile(filepath.Join(root, "notes"), []byte("The quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestSniffContainer(t *testing.T) {
+	for _, src := range sniffcopy {
+		var f, err = os.Open(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var maker jnt.JointMaker
+		if maker, err = jnt.SniffContainer(f); err != nil {
+			t.Fatal(err)
+		}
+		if maker == nil {
+			t.Fatalf("%s: content is not recognized", src)
+		}
+		f.Close()
+	}
+
+	var f, err = os.Open("testdata/external.iso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var maker jnt.JointMaker
+	if maker, err = jnt.SniffContainer(io.NewSectionReader(f, 0x9000, 0x1000)); err != nil {
+		t.Fatal(err)
+	}
+	if maker != nil {
+		t.Fatal("part of file should not be recognized as container")
+	}
+}
+
+func TestSniffPool(t *testing.T) {
+	var root = makeSniffDir(t)
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	// content recognition is disabled by default
+	if _, err := jp.Stat(root + "/archive/fox.txt"); err == nil {
+		t.Fatal("file without container extension should not be opened as container")
+	}
+
+	jnt.Cfg.SniffContent = true
+	defer func() { jnt.Cfg.SniffContent = false }()
+
+	for fpath, size := range snifffiles {
+		var b, err = fs.ReadFile(jp, root+"/"+fpath)
+		if err != nil {
+			t.Fatalf("%s: %v", fpath, err)
+		}
+		if size >= 0 && len(b) != size {
+			t.Fatalf("%s: file size does not match", fpath)
+		}
+	}
+
+	var fi, err = jp.Stat(root + "/notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.IsDir() {
+		t.Fatal("text file should not be recognized as container")
+	}
+
+	for name := range sniffcopy {
+		if fi, err = jp.Stat(root + "/" + name); err != nil {
+			t.Fatal(err)
+		}
+		if !fi.IsDir() || fi.(jnt.JointFileInfo).IsRealDir() {
+			t.Fatalf("%s: container should be represented as directory", name)
+		}
+	}
+
+	var list []fs.DirEntry
+	if list, err = jp.ReadDir(root); err != nil {
+		t.Fatal(err)
+	}
+	for _, de := range list {
+		if de.IsDir() != (de.Name() != "notes") {
+			t.Fatalf("%s: directory state of entry does not match", de.Name())
+		}
+	}
+}
+
+// checkSniffRead reads plain file through the pool with
+// content recognition, and compares it with local file.
+func checkSniffRead(t *testing.T, jp *jnt.JointPool, fullpath, fpath string) {
+	var want, err = os.ReadFile(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ { // first read recognizes file, second uses the result
+		var got []byte
+		if got, err = fs.ReadFile(jp, fullpath); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("content of file does not match, %d bytes read, %d expected", len(got), len(want))
+		}
+	}
+}
+
+func TestSniffHttpFile(t *testing.T) {
+	var srv = httptest.NewServer(http.FileServer(http.Dir(".")))
+	defer srv.Close()
+
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	jnt.Cfg.SniffContent = true
+	defer func() { jnt.Cfg.SniffContent = false }()
+
+	checkSniffRead(t, jp, srv.URL+"/go.mod", "go.mod")
+}
+
+func TestSniffFtpFile(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	jnt.Cfg.SniffContent = true
+	defer func() { jnt.Cfg.SniffContent = false }()
+
+	checkSniffRead(t, jp, srvs.FtpAddr+"/go.mod", "go.mod")
+	checkSniffRead(t, jp, srvs.FtpAddr+"/README.md", "README.md")
+}
+
+func TestSniffCache(t *testing.T) {
+	var root = makeSniffDir(t)
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	jnt.Cfg.SniffContent = true
+	defer func() { jnt.Cfg.SniffContent = false }()
+	var size = jnt.Cfg.SniffCacheSize
+	jnt.Cfg.SniffCacheSize = 2 // container and file in it
+	defer func() { jnt.Cfg.SniffCacheSize = size }()
+
+	if _, err := fs.ReadFile(jp, root+"/archive/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if jp.SniffedMaker(root+"/archive") == nil {
+		t.Fatal("container is not remembered")
+	}
+
+	// the oldest result is removed on overflow
+	if _, err := fs.ReadFile(jp, root+"/disk1/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if jp.SniffedMaker(root+"/archive") != nil {
+		t.Fatal("number of stored results is not limited")
+	}
+	if jp.SniffedMaker(root+"/disk1") == nil {
+		t.Fatal("container is not remembered")
+	}
+	if _, err := fs.ReadFile(jp, root+"/archive/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	jp.ClearSniffed()
+	if jp.SniffedMaker(root+"/archive") != nil {
+		t.Fatal("results are not cleared")
+	}
+}
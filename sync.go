@@ -0,0 +1,394 @@
+package joint
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyncOp is kind of file operation performed by Sync.
+type SyncOp int
+
+const (
+	SyncCopy   SyncOp = iota // copy new file
+	SyncUpdate               // rewrite changed file
+	SyncMkdir                // create new directory
+	SyncDelete               // delete extraneous file or empty directory
+)
+
+// String returns name of operation.
+func (op SyncOp) String() string {
+	switch op {
+	case SyncCopy:
+		return "copy"
+	case SyncUpdate:
+		return "update"
+	case SyncMkdir:
+		return "mkdir"
+	case SyncDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// SyncAction describes file operation performed by Sync.
+type SyncAction struct {
+	Op   SyncOp
+	Src  string // full path of source file, it's empty on delete
+	Dst  string // full path of destination file
+	Size int64  // size of copied file
+	Err  error  // error of operation
+}
+
+// SyncOptions determines how Sync compares and transfers files.
+type SyncOptions struct {
+	// Delete files and directories at destination which are absent at source.
+	Delete bool
+	// Report operations without any changes at destination.
+	DryRun bool
+	// Glob patterns of skipped files in the same format as in WalkOptions.
+	// Excluded files at destination are never deleted.
+	Exclude []string
+	// Compare content of files with the same size by SHA-256 hash
+	// instead of modification time.
+	Checksum bool
+	// Maximum difference of modification times of files which are
+	// considered as the same. It helps with servers that provide time
+	// with low precision, such as FTP.
+	ModifyWindow time.Duration
+	// Maximum number of concurrent transfers at each joints cache.
+	// DefWalkJoints is used if it's zero.
+	Joints int
+	// Callback called for each operation after it's performed.
+	// It's never called concurrently.
+	Report func(SyncAction)
+}
+
+// SyncResult contains numbers of performed operations.
+type SyncResult struct {
+	Copied  int   // number of copied new files
+	Updated int   // number of rewritten changed files
+	Dirs    int   // number of created directories
+	Deleted int   // number of deleted files and directories
+	Skipped int   // number of files which are the same
+	Bytes   int64 // number of transferred bytes
+}
+
+// tree returns infos of all files at given directory by relative paths.
+func (jp *JointPool) tree(ctx context.Context, root string, opts *SyncOptions) (files map[string]fs.FileInfo, err error) {
+	files = map[string]fs.FileInfo{}
+	err = jp.Walk(ctx, root, WalkOptions{
+		Exclude: opts.Exclude,
+		Joints:  opts.Joints,
+	}, func(ent WalkEntry) error {
+		if ent.Err != nil {
+			return ent.Err
+		}
+		if ent.Depth == 0 {
+			return nil
+		}
+		var fi, err = ent.Info()
+		if err != nil {
+			return err
+		}
+		files[strings.TrimPrefix(ent.Path, JoinPath(root, "/"))] = fi
+		return nil
+	})
+	return
+}
+
+// hashfile returns SHA-256 hash of file content. Containers are read as files.
+func (jp *JointPool) hashfile(fullpath string) (sum []byte, err error) {
+	var jw, fpath, err1 = jp.getjoint(fullpath, len(fullpath)-1)
+	if err1 != nil {
+		return nil, err1
+	}
+	defer func() { release(jw, err) }()
+	if _, err = jw.Open(fpath); err != nil {
+		return
+	}
+	var h = sha256.New()
+	if _, err = io.Copy(h, jw.Joint); err != nil {
+		return
+	}
+	return h.Sum(nil), nil
+}
+
+// synctask is file transfer performed by Sync.
+type synctask struct {
+	act    SyncAction
+	verify bool     // compare content of files before transfer
+	keys   []string // keys of caches of source and destination
+}
+
+// syncer keeps state of synchronization.
+type syncer struct {
+	jp   *JointPool
+	ctx  context.Context
+	opts *SyncOptions
+	res  SyncResult
+	errs []error
+	mux  sync.Mutex
+}
+
+// done registers performed operation.
+func (s *syncer) done(act SyncAction) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if act.Err != nil {
+		s.errs = append(s.errs, act.Err)
+	} else {
+		switch act.Op {
+		case SyncCopy:
+			s.res.Copied++
+			s.res.Bytes += act.Size
+		case SyncUpdate:
+			s.res.Updated++
+			s.res.Bytes += act.Size
+		case SyncMkdir:
+			s.res.Dirs++
+		case SyncDelete:
+			s.res.Deleted++
+		}
+	}
+	if s.opts.Report != nil {
+		s.opts.Report(act)
+	}
+}
+
+// skip registers file that is the same at destination.
+func (s *syncer) skip() {
+	s.mux.Lock()
+	s.res.Skipped++
+	s.mux.Unlock()
+}
+
+// same compares modification times with options window.
+func (s *syncer) same(t1, t2 time.Time) bool {
+	if t1.IsZero() || t2.IsZero() {
+		return true
+	}
+	var d = t1.Sub(t2)
+	if d < 0 {
+		d = -d
+	}
+	return d <= s.opts.ModifyWindow
+}
+
+// transfer performs file copying task.
+func (s *syncer) transfer(t synctask) {
+	if t.verify {
+		var sum1, sum2 []byte
+		if sum1, t.act.Err = s.jp.hashfile(t.act.Src); t.act.Err == nil {
+			sum2, t.act.Err = s.jp.hashfile(t.act.Dst)
+		}
+		if t.act.Err == nil && bytes.Equal(sum1, sum2) {
+			s.skip()
+			return
+		}
+	}
+	if t.act.Err == nil && !s.opts.DryRun {
+		t.act.Err = s.jp.Copy(s.ctx, t.act.Src, t.act.Dst, CopyOptions{
+			Overwrite:     OverwriteAlways,
+			PreserveMtime: true,
+		})
+	}
+	s.done(t.act)
+}
+
+// run performs file copying tasks concurrently with limit
+// of concurrent transfers at each joints cache. Tasks are
+// launched when their caches have free slots.
+func (s *syncer) run(tasks []synctask) {
+	var limit = s.opts.Joints
+	if limit <= 0 {
+		limit = DefWalkJoints
+	}
+	for i, t := range tasks {
+		var k1, _, _ = splitkey(&s.jp.sniffed, t.act.Src, len(t.act.Src))
+		var k2, _, _ = splitkey(&s.jp.sniffed, t.act.Dst, len(t.act.Dst))
+		if tasks[i].keys = []string{k1}; k2 != k1 {
+			tasks[i].keys = append(tasks[i].keys, k2)
+		}
+	}
+
+	var results = make(chan synctask)
+	var running = map[string]int{}
+	var count int
+	var free = func(t synctask) bool {
+		for _, key := range t.keys {
+			if running[key] >= limit {
+				return false
+			}
+		}
+		return true
+	}
+	var launch = func() {
+		if s.ctx.Err() != nil {
+			return
+		}
+		var i int
+		for _, t := range tasks {
+			if !free(t) {
+				tasks[i] = t
+				i++
+				continue
+			}
+			for _, key := range t.keys {
+				running[key]++
+			}
+			count++
+			go func(t synctask) {
+				s.transfer(t)
+				results <- t
+			}(t)
+		}
+		clear(tasks[i:])
+		tasks = tasks[:i]
+	}
+
+	launch()
+	for count > 0 {
+		var t = <-results
+		for _, key := range t.keys {
+			running[key]--
+		}
+		count--
+		launch()
+	}
+}
+
+// Sync performs one-way synchronization of destination directory with
+// source directory, both are given by full paths, and can be placed at
+// any joints, i.e. content of ISO-image can be synchronized with local
+// directory. New files are copied, files with another size or
+// modification time are rewritten, and extraneous files at destination
+// are deleted if it's set in options. Modification times of copied
+// files are preserved, if destination joint supports it. Containers
+// are compared and copied as files. Files are transferred concurrently,
+// and Sync continues on errors of single files, and returns all of them.
+func (jp *JointPool) Sync(ctx context.Context, src, dst string, opts SyncOptions) (res SyncResult, err error) {
+	if len(src) > 1 {
+		src = strings.TrimSuffix(src, "/")
+	}
+	if len(dst) > 1 {
+		dst = strings.TrimSuffix(dst, "/")
+	}
+	var fi fs.FileInfo
+	if fi, err = jp.StatContext(ctx, src); err != nil {
+		return
+	}
+	if !fi.IsDir() {
+		err = &fs.PathError{Op: "sync", Path: src, Err: fs.ErrInvalid}
+		return
+	}
+	if dst == src || strings.HasPrefix(dst, JoinPath(src, "/")) {
+		err = &fs.PathError{Op: "sync", Path: dst, Err: ErrCopySelf}
+		return
+	}
+
+	var s = syncer{
+		jp:   jp,
+		ctx:  ctx,
+		opts: &opts,
+	}
+	var srctree, dsttree map[string]fs.FileInfo
+	if srctree, err = jp.tree(ctx, src, &opts); err != nil {
+		return
+	}
+	if _, err = jp.StatContext(ctx, dst); err == nil {
+		if dsttree, err = jp.tree(ctx, dst, &opts); err != nil {
+			return
+		}
+	} else if errors.Is(err, fs.ErrNotExist) {
+		var act = SyncAction{Op: SyncMkdir, Src: src, Dst: dst}
+		if !opts.DryRun {
+			if act.Err = jp.Mkdir(dst); act.Err != nil {
+				return res, act.Err
+			}
+		}
+		s.done(act)
+		err = nil
+	} else {
+		return
+	}
+
+	var srclist = make([]string, 0, len(srctree))
+	for rel := range srctree {
+		srclist = append(srclist, rel)
+	}
+	sort.Strings(srclist)
+	var dstlist = make([]string, 0, len(dsttree))
+	for rel := range dsttree {
+		if _, ok := srctree[rel]; !ok {
+			dstlist = append(dstlist, rel)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(dstlist))) // nested files first
+
+	// create directories in lexical order, so parents are created first
+	var tasks []synctask
+	for _, rel := range srclist {
+		var sfi = srctree[rel]
+		var dfi, ok = dsttree[rel]
+		var act = SyncAction{Src: JoinPath(src, rel), Dst: JoinPath(dst, rel)}
+		if isrealdir(ToDirEntry(sfi)) {
+			if ok {
+				if !isrealdir(ToDirEntry(dfi)) {
+					act.Op, act.Err = SyncMkdir, &fs.PathError{Op: "sync", Path: act.Dst, Err: fs.ErrExist}
+					s.done(act)
+				}
+				continue
+			}
+			act.Op = SyncMkdir
+			if !opts.DryRun {
+				act.Err = jp.Mkdir(act.Dst)
+			}
+			s.done(act)
+			continue
+		}
+		act.Size = sfi.Size()
+		if !ok {
+			act.Op = SyncCopy
+			tasks = append(tasks, synctask{act: act})
+			continue
+		}
+		act.Op = SyncUpdate
+		if isrealdir(ToDirEntry(dfi)) {
+			act.Err = &fs.PathError{Op: "sync", Path: act.Dst, Err: fs.ErrExist}
+			s.done(act)
+			continue
+		}
+		if sfi.Size() != dfi.Size() {
+			tasks = append(tasks, synctask{act: act})
+		} else if opts.Checksum {
+			tasks = append(tasks, synctask{act: act, verify: true})
+		} else if !s.same(sfi.ModTime(), dfi.ModTime()) {
+			tasks = append(tasks, synctask{act: act})
+		} else {
+			s.skip()
+		}
+	}
+	s.run(tasks)
+
+	if opts.Delete && ctx.Err() == nil {
+		for _, rel := range dstlist {
+			var act = SyncAction{Op: SyncDelete, Dst: JoinPath(dst, rel)}
+			if !opts.DryRun {
+				act.Err = jp.Remove(act.Dst)
+			}
+			s.done(act)
+		}
+	}
+	if err = ctx.Err(); err != nil {
+		return s.res, err
+	}
+	return s.res, errors.Join(s.errs...)
+}
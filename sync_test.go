@@ -0,0 +1,124 @@
+package joint_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+func TestSync(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+	var ctx = context.Background()
+	var src = "testdata/external.iso/data"
+	var dst = filepath.ToSlash(t.TempDir()) + "/data"
+
+	var res, err = jp.Sync(ctx, src, dst, jnt.SyncOptions{Exclude: []string{"lorem3.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Copied != 7 || res.Dirs != 4 || res.Updated != 0 || res.Skipped != 0 {
+		t.Fatalf("synchronization result does not match: %+v", res)
+	}
+	if _, err = os.Stat(dst + "/lorem3.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("excluded file should not be copied")
+	}
+	var b []byte
+	if b, err = os.ReadFile(dst + "/docs/doc1.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 445 {
+		t.Fatal("size of copied file does not match")
+	}
+
+	// all files are the same at second call
+	if res, err = jp.Sync(ctx, src, dst, jnt.SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if res.Copied != 1 || res.Skipped != 7 {
+		t.Fatalf("synchronization result does not match: %+v", res)
+	}
+
+	// changed and extraneous files
+	if err = os.WriteFile(dst+"/docs/doc1.txt", []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(dst+"/docs/extra.txt", []byte("extra"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var acts = map[string]jnt.SyncOp{}
+	var opts = jnt.SyncOptions{
+		Delete: true,
+		DryRun: true,
+		Report: func(act jnt.SyncAction) {
+			acts[act.Dst] = act.Op
+		},
+	}
+	if res, err = jp.Sync(ctx, src, dst, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(acts) != 2 || acts[dst+"/docs/doc1.txt"] != jnt.SyncUpdate || acts[dst+"/docs/extra.txt"] != jnt.SyncDelete {
+		t.Fatalf("reported actions does not match: %v", acts)
+	}
+	if _, err = os.Stat(dst + "/docs/extra.txt"); err != nil {
+		t.Fatal("dry run should not change destination")
+	}
+	opts.DryRun = false
+	if res, err = jp.Sync(ctx, src, dst, opts); err != nil {
+		t.Fatal(err)
+	}
+	if res.Updated != 1 || res.Deleted != 1 {
+		t.Fatalf("synchronization result does not match: %+v", res)
+	}
+	if _, err = os.Stat(dst + "/docs/extra.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("extraneous file should be deleted")
+	}
+}
+
+func TestSyncChecksum(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+	var ctx = context.Background()
+	var src = "testdata/external.iso/data/docs"
+	var dst = filepath.ToSlash(t.TempDir())
+
+	if _, err := jp.Sync(ctx, src, dst, jnt.SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// change content with the same size and modification time
+	var fpath = dst + "/doc1.txt"
+	var fi, err = os.Stat(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b []byte
+	if b, err = os.ReadFile(fpath); err != nil {
+		t.Fatal(err)
+	}
+	b[0] ^= 0x20
+	if err = os.WriteFile(fpath, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(fpath, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+
+	var res jnt.SyncResult
+	if res, err = jp.Sync(ctx, src, dst, jnt.SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if res.Updated != 0 {
+		t.Fatal("files with the same size and time should be skipped")
+	}
+	if res, err = jp.Sync(ctx, src, dst, jnt.SyncOptions{Checksum: true}); err != nil {
+		t.Fatal(err)
+	}
+	if res.Updated != 1 || res.Skipped != 1 {
+		t.Fatalf("synchronization result does not match: %+v", res)
+	}
+}
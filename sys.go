@@ -2,8 +2,10 @@ package joint
 
 import (
 	"errors"
+	"io"
 	"io/fs"
 	"os"
+	"time"
 )
 
 type SysJoint struct {
@@ -58,7 +60,7 @@ func (j *SysJoint) Size() (int64, error) {
 func (j *SysJoint) ReadDir(n int) ([]fs.DirEntry, error) {
 	var errs []error
 	var list, err = j.File.ReadDir(n)
-	if err != nil {
+	if err != nil && err != io.EOF {
 		errs = append(errs, err)
 	}
 	for i, de := range list {
@@ -69,7 +71,10 @@ func (j *SysJoint) ReadDir(n int) ([]fs.DirEntry, error) {
 		}
 		list[i] = ToDirEntry(fi)
 	}
-	return list, errors.Join(errs...)
+	if len(errs) > 0 {
+		return list, errors.Join(errs...)
+	}
+	return list, err // io.EOF should not be wrapped
 }
 
 func (j *SysJoint) Stat() (fs.FileInfo, error) {
@@ -81,3 +86,56 @@ func (j *SysJoint) Info(fpath string) (fs.FileInfo, error) {
 	var fi, err = os.Stat(JoinPath(j.dir, fpath))
 	return ToFileInfo(fi), err
 }
+
+// Lstat returns file info without following of symbolic link.
+func (j *SysJoint) Lstat(fpath string) (fs.FileInfo, error) {
+	var fi, err = os.Lstat(JoinPath(j.dir, fpath))
+	return ToFileInfo(fi), err
+}
+
+// ReadLink returns target of symbolic link.
+func (j *SysJoint) ReadLink(fpath string) (string, error) {
+	return os.Readlink(JoinPath(j.dir, fpath))
+}
+
+// Put creates or truncates file, and writes content of given reader.
+func (j *SysJoint) Put(fpath string, r io.Reader) (err error) {
+	var f *os.File
+	if f, err = os.Create(JoinPath(j.dir, fpath)); err != nil {
+		return
+	}
+	_, err = io.Copy(f, r)
+	return errors.Join(err, f.Close())
+}
+
+// PutFrom writes content of given reader to file from given offset.
+func (j *SysJoint) PutFrom(fpath string, r io.Reader, offset int64) (err error) {
+	var f *os.File
+	if f, err = os.OpenFile(JoinPath(j.dir, fpath), os.O_WRONLY|os.O_CREATE, 0644); err != nil {
+		return
+	}
+	if _, err = f.Seek(offset, io.SeekStart); err == nil {
+		_, err = io.Copy(f, r)
+	}
+	return errors.Join(err, f.Close())
+}
+
+// Mkdir creates new directory.
+func (j *SysJoint) Mkdir(fpath string) error {
+	return os.Mkdir(JoinPath(j.dir, fpath), 0755)
+}
+
+// Remove removes file or empty directory.
+func (j *SysJoint) Remove(fpath string) error {
+	return os.Remove(JoinPath(j.dir, fpath))
+}
+
+// Rename moves file or directory.
+func (j *SysJoint) Rename(oldpath, newpath string) error {
+	return os.Rename(JoinPath(j.dir, oldpath), JoinPath(j.dir, newpath))
+}
+
+// Chtimes changes modification time of file.
+func (j *SysJoint) Chtimes(fpath string, mtime time.Time) error {
+	return os.Chtimes(JoinPath(j.dir, fpath), mtime, mtime)
+}
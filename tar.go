@@ -0,0 +1,237 @@
+package joint
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTarEmpty = errors.New("tar-archive has zero size")
+)
+
+// TarFS is read-only file system of uncompressed tar-archive placed
+// at ReaderAt. Archive headers are indexed on opening, and files
+// content is read directly from archive without extraction.
+// Directories missed at archive are recognized by files paths.
+// Sparse files are skipped.
+type TarFS struct {
+	files map[string]TarFileInfo
+	dirs  map[string][]fs.DirEntry
+	r     io.ReaderAt
+}
+
+// OpenTar indexes tar-archive placed in given reader.
+func OpenTar(r io.ReaderAt, size int64) (t *TarFS, err error) {
+	t = &TarFS{
+		files: map[string]TarFileInfo{
+			".": {name: ".", mode: fs.ModeDir | 0555},
+		},
+		dirs: map[string][]fs.DirEntry{},
+		r:    r,
+	}
+	var sr = io.NewSectionReader(r, 0, size)
+	var tr = tar.NewReader(sr)
+	for {
+		var hdr *tar.Header
+		if hdr, err = tr.Next(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		var fpath = path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if !fs.ValidPath(fpath) || fpath == "." || hdr.Typeflag == tar.TypeGNUSparse {
+			continue
+		}
+		var offset int64
+		if offset, err = sr.Seek(0, io.SeekCurrent); err != nil { // tar reader is positioned to file content
+			return nil, err
+		}
+		var fi = TarFileInfo{
+			name:   path.Base(fpath),
+			mode:   hdr.FileInfo().Mode(),
+			mtime:  hdr.ModTime,
+			offset: offset,
+		}
+		if hdr.Typeflag == tar.TypeReg || hdr.Typeflag == tar.TypeRegA {
+			fi.size = hdr.Size
+		}
+		t.add(fpath, fi)
+	}
+	for dir := range t.dirs {
+		sort.Slice(t.dirs[dir], func(i, j int) bool {
+			return t.dirs[dir][i].Name() < t.dirs[dir][j].Name()
+		})
+	}
+	return t, nil
+}
+
+// add puts file to index, and creates missed parent directories.
+func (t *TarFS) add(fpath string, fi TarFileInfo) {
+	if _, ok := t.files[fpath]; ok { // replace previous entry
+		var list = t.dirs[path.Dir(fpath)]
+		for i, de := range list {
+			if de.Name() == fi.name {
+				list[i] = fi
+				break
+			}
+		}
+		t.files[fpath] = fi
+		return
+	}
+	t.files[fpath] = fi
+	var dir = path.Dir(fpath)
+	t.dirs[dir] = append(t.dirs[dir], fi)
+	if _, ok := t.files[dir]; !ok {
+		t.add(dir, TarFileInfo{name: path.Base(dir), mode: fs.ModeDir | 0555})
+	}
+}
+
+// Stat implements fs.StatFS interface.
+func (t *TarFS) Stat(fpath string) (fs.FileInfo, error) {
+	if !fs.ValidPath(fpath) {
+		return nil, &fs.PathError{Op: "stat", Path: fpath, Err: fs.ErrInvalid}
+	}
+	var fi, ok = t.files[fpath]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: fpath, Err: fs.ErrNotExist}
+	}
+	return fi, nil
+}
+
+// ReadDir implements fs.ReadDirFS interface.
+func (t *TarFS) ReadDir(fpath string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(fpath) {
+		return nil, &fs.PathError{Op: "readdir", Path: fpath, Err: fs.ErrInvalid}
+	}
+	var fi, ok = t.files[fpath]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: fpath, Err: fs.ErrNotExist}
+	}
+	if !fi.IsRealDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: fpath, Err: fs.ErrInvalid}
+	}
+	var list = make([]fs.DirEntry, len(t.dirs[fpath]))
+	copy(list, t.dirs[fpath])
+	return list, nil
+}
+
+// Open implements fs.FS interface.
+func (t *TarFS) Open(fpath string) (fs.File, error) {
+	if !fs.ValidPath(fpath) {
+		return nil, &fs.PathError{Op: "open", Path: fpath, Err: fs.ErrInvalid}
+	}
+	var fi, ok = t.files[fpath]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: fpath, Err: fs.ErrNotExist}
+	}
+	if fi.IsRealDir() {
+		var list, _ = t.ReadDir(fpath)
+		return &imgdir{fi: fi, list: list}, nil
+	}
+	return &imgfile{fi: fi, SectionReader: io.NewSectionReader(t.r, fi.offset, fi.size)}, nil
+}
+
+// TarFileInfo describes file at tar-archive,
+// and provides fs.FileInfo and fs.DirEntry implementation.
+type TarFileInfo struct {
+	name   string
+	size   int64
+	mode   fs.FileMode
+	mtime  time.Time
+	offset int64 // file content offset at archive
+}
+
+// fs.FileInfo implementation.
+func (fi TarFileInfo) Name() string {
+	return fi.name
+}
+
+// fs.FileInfo implementation.
+func (fi TarFileInfo) Size() int64 {
+	return fi.size
+}
+
+// fs.FileInfo implementation.
+func (fi TarFileInfo) Mode() fs.FileMode {
+	if fi.mode.IsRegular() && IsTypeContainer(fi.name) {
+		return fi.mode | fs.ModeDir
+	}
+	return fi.mode
+}
+
+// fs.FileInfo implementation.
+func (fi TarFileInfo) ModTime() time.Time {
+	return fi.mtime
+}
+
+// fs.FileInfo implementation.
+func (fi TarFileInfo) IsDir() bool {
+	return fi.IsRealDir() || (fi.mode.IsRegular() && IsTypeContainer(fi.name))
+}
+
+func (fi TarFileInfo) IsRealDir() bool {
+	return fi.mode.IsDir()
+}
+
+func (fi TarFileInfo) Type() fs.FileMode {
+	return fi.Mode().Type()
+}
+
+// Info provided for fs.DirEntry compatibility and returns object itself.
+func (fi TarFileInfo) Info() (fs.FileInfo, error) {
+	return fi, nil
+}
+
+// fs.FileInfo implementation. Returns structure itself.
+func (fi TarFileInfo) Sys() interface{} {
+	return fi
+}
+
+func (fi TarFileInfo) String() string {
+	return fs.FormatDirEntry(fi)
+}
+
+// TarJoint opens uncompressed tar-archive placed at base joint
+// and provides read-only access to its files.
+// Key is external path to archive file.
+type TarJoint struct {
+	Base Joint
+	FSJoint
+}
+
+func (j *TarJoint) Make(base Joint, arcpath string) (err error) {
+	var size int64
+	if j.Base, size, err = openbase(base, arcpath); err != nil {
+		return
+	}
+	if size == 0 { // directory can have zero size at some servers
+		return ErrTarEmpty
+	}
+	if j.FS, err = OpenTar(j.Base, size); err != nil {
+		return
+	}
+	return
+}
+
+func (j *TarJoint) Cleanup() (err error) {
+	j.FSJoint.Cleanup()
+	if j.Base != nil {
+		err = j.Base.Cleanup()
+		j.Base = nil
+	}
+	return err
+}
+
+func (j *TarJoint) Stat() (fs.FileInfo, error) {
+	if j.path == "." { // base archive
+		return statbase(j.Base)
+	}
+	return j.FSJoint.Stat()
+}
@@ -0,0 +1,243 @@
+package joint
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Tracer starts spans of pool operations. It's hook to connect
+// tracing system, such as OpenTelemetry, without dependency on it.
+// Spans are nested by context given to operations of the pool.
+type Tracer interface {
+	// Start begins span with given name and attributes,
+	// and returns context that carries it to nested spans.
+	Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span)
+}
+
+// Span is operation started by Tracer.
+type Span interface {
+	// SetAttrs sets attributes known after start of span.
+	SetAttrs(attrs ...slog.Attr)
+	// End finishes span, err is failure of operation or nil.
+	End(err error)
+}
+
+// nospan is Span that does nothing.
+type nospan struct{}
+
+func (nospan) SetAttrs(...slog.Attr) {}
+func (nospan) End(error)             {}
+
+// notracer is Tracer that does nothing.
+type notracer struct{}
+
+func (notracer) Start(ctx context.Context, _ string, _ ...slog.Attr) (context.Context, Span) {
+	return ctx, nospan{}
+}
+
+// notrace is default tracer that starts no spans.
+var notrace Tracer = notracer{}
+
+// WithTracer sets tracer of operations of JointPool and of all its caches.
+// Operations are not traced by default.
+func WithTracer(tr Tracer) PoolOption {
+	return func(jp *JointPool) {
+		jp.tr = tr
+	}
+}
+
+// tracer returns tracer of the pool.
+func (jp *JointPool) tracer() Tracer {
+	if jp.tr == nil {
+		return notrace
+	}
+	return jp.tr
+}
+
+// SetTracer sets tracer of cache operations. It should be
+// set before cache usage, JointPool sets it by WithTracer option.
+func (jc *JointCache) SetTracer(tr Tracer) {
+	if tr == nil {
+		tr = notrace
+	}
+	jc.tr = tr
+}
+
+// spanerr returns error of operation to end its span,
+// io.EOF is not a failure.
+func spanerr(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// tracectx is context of calls to traced chain of joints. It's context
+// of operation that took the joint from the cache, and it's nil while
+// joint is at the cache, so calls made by containers to their bases
+// are nested into span of this operation.
+type tracectx struct {
+	ctx context.Context
+}
+
+// start begins span for call with given name, if it's traced.
+func (tc *tracectx) start(tr Tracer, name string, attrs ...slog.Attr) (context.Context, Span) {
+	if tc == nil || tc.ctx == nil {
+		return nil, nospan{}
+	}
+	return tr.Start(tc.ctx, name, attrs...)
+}
+
+// swap sets new context of calls, and returns previous one.
+func (tc *tracectx) swap(ctx context.Context) (prev context.Context) {
+	if tc == nil || ctx == nil {
+		return
+	}
+	prev, tc.ctx = tc.ctx, ctx
+	return
+}
+
+// settrace binds chain of joint taken from the cache to context
+// of calls. Joints made with tracing are registered in the cache.
+func (jc *JointCache) settrace(jw *JointWrap, tc *tracectx, ctx context.Context) {
+	jc.mux.Lock()
+	if tc != nil {
+		if jc.traced == nil {
+			jc.traced = map[Joint]*tracectx{}
+		}
+		jc.traced[jw.Joint] = tc
+	} else {
+		tc = jc.traced[jw.Joint]
+	}
+	jc.mux.Unlock()
+	if tc != nil {
+		tc.ctx = ctx
+	}
+	jw.tc = tc
+}
+
+// untrace removes context of joint that leaves the cache.
+func (jc *JointCache) untrace(j Joint) {
+	jc.mux.Lock()
+	delete(jc.traced, j)
+	jc.mux.Unlock()
+}
+
+// tracefile sets context of calls to joint of opened file,
+// and returns previous one.
+func tracefile(f fs.File, ctx context.Context) context.Context {
+	if jw, ok := f.(JointWrap); ok {
+		return jw.tc.swap(ctx)
+	}
+	return nil
+}
+
+// tracestage is embedded into network joints to trace stages
+// of their making, such as dialing and login, as separate spans.
+// It's set by tracemake while joint is made.
+type tracestage struct {
+	ctx context.Context
+	tr  Tracer
+	ml  *makelog
+}
+
+// stagehook returns hook to set tracer of stages.
+func (s *tracestage) stagehook() *tracestage {
+	return s
+}
+
+// stage begins span of making stage with given name,
+// and writes record about the stage at the end of span.
+func (s *tracestage) stage(name string, attrs ...slog.Attr) Span {
+	var span Span = nospan{}
+	if s.tr != nil {
+		_, span = s.tr.Start(s.ctx, name, attrs...)
+	}
+	if s.ml == nil {
+		return span
+	}
+	return &stagespan{
+		Span:  span,
+		ml:    s.ml,
+		stage: strings.ToLower(strings.TrimPrefix(name, "Joint.")),
+		t0:    time.Now(),
+	}
+}
+
+// tracemake makes joint on given base within span. If base is traced,
+// calls to base are nested into this span while joint is made.
+// Stages of network joint are logged by given makelog if it's not nil.
+func tracemake(ctx context.Context, tr Tracer, ml *makelog, j, base Joint, addr string) (err error) {
+	var ctx1, span = tr.Start(ctx, "Joint.Make",
+		slog.String("joint", fmt.Sprintf("%T", j)),
+		slog.String("addr", Redact(addr)))
+	defer func() { span.End(err) }()
+	if tj, ok := base.(*tracejoint); ok {
+		var prev = tj.tc.swap(ctx1)
+		defer func() { tj.tc.ctx = prev }()
+	}
+	if sh, ok := j.(interface{ stagehook() *tracestage }); ok {
+		var h = sh.stagehook()
+		h.ctx, h.tr, h.ml = ctx1, tr, ml
+		defer func() { h.ctx, h.tr, h.ml = nil, nil, nil }()
+	}
+	return j.Make(base, addr)
+}
+
+// tracejoint starts spans for calls to wrapped joint while its chain
+// has context. It's base of containers, so calls made by containers
+// to resolve their content are traced.
+type tracejoint struct {
+	Joint
+	tr Tracer
+	tc *tracectx // context shared by chain of joints
+}
+
+// start begins span for call with given name, if it's traced.
+func (j *tracejoint) start(name string, attrs ...slog.Attr) Span {
+	var _, span = j.tc.start(j.tr, name, attrs...)
+	return span
+}
+func (j *tracejoint) Open(fpath string) (f fs.File, err error) {
+	var span = j.start("Joint.Open", slog.String("path", fpath))
+	defer func() { span.End(err) }()
+	if _, err = j.Joint.Open(fpath); err != nil {
+		return
+	}
+	return j, nil
+}
+
+func (j *tracejoint) Stat() (fi fs.FileInfo, err error) {
+	var span = j.start("Joint.Stat")
+	defer func() { span.End(err) }()
+	return j.Joint.Stat()
+}
+
+func (j *tracejoint) ReadDir(n int) (list []fs.DirEntry, err error) {
+	var span = j.start("Joint.ReadDir", slog.Int("n", n))
+	defer func() { span.End(spanerr(err)) }()
+	return j.Joint.ReadDir(n)
+}
+
+func (j *tracejoint) Size() (size int64, err error) {
+	var span = j.start("Joint.Size")
+	defer func() { span.End(err) }()
+	return j.Joint.Size()
+}
+
+func (j *tracejoint) Read(b []byte) (n int, err error) {
+	var span = j.start("Joint.Read", slog.Int("len", len(b)))
+	defer func() { span.End(spanerr(err)) }()
+	return j.Joint.Read(b)
+}
+
+func (j *tracejoint) ReadAt(b []byte, off int64) (n int, err error) {
+	var span = j.start("Joint.ReadAt", slog.Int("len", len(b)), slog.Int64("off", off))
+	defer func() { span.End(spanerr(err)) }()
+	return j.Joint.ReadAt(b, off)
+}
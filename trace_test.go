@@ -0,0 +1,198 @@
+package joint_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+// recspan is span recorded by rectracer.
+type recspan struct {
+	name   string
+	parent *recspan
+	attrs  map[string]slog.Value
+	err    error
+	ended  bool
+}
+
+func (s *recspan) SetAttrs(attrs ...slog.Attr) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recspan) End(err error) {
+	s.err, s.ended = err, true
+}
+
+// within returns whether span is nested into span with given name.
+func (s *recspan) within(name string) bool {
+	for p := s.parent; p != nil; p = p.parent {
+		if p.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type spankey struct{}
+
+// rectracer is tracer that records all started spans.
+type rectracer struct {
+	spans []*recspan
+	mux   sync.Mutex
+}
+
+func (t *rectracer) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, jnt.Span) {
+	var parent, _ = ctx.Value(spankey{}).(*recspan)
+	var s = &recspan{name: name, parent: parent, attrs: map[string]slog.Value{}}
+	s.SetAttrs(attrs...)
+	t.mux.Lock()
+	t.spans = append(t.spans, s)
+	t.mux.Unlock()
+	return context.WithValue(ctx, spankey{}, s), s
+}
+
+// take returns recorded spans with given name, and resets the record.
+func (t *rectracer) take(name string) (spans []*recspan, all []*recspan) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	for _, s := range t.spans {
+		if s.name == name {
+			spans = append(spans, s)
+		}
+	}
+	all, t.spans = t.spans, nil
+	return
+}
+
+func TestPoolTracer(t *testing.T) {
+	var tr = &rectracer{}
+	var jp = jnt.NewJointPool(jnt.WithTracer(tr))
+	defer jp.Close()
+
+	var ctx, span = tr.Start(context.Background(), "test")
+	if _, err := jp.ReadDirContext(ctx, "testdata/external.iso/disk/internal.iso"); err != nil {
+		t.Fatal(err)
+	}
+	span.End(nil)
+
+	var gets, all = tr.take("JointCache.Get")
+	for _, s := range all {
+		if !s.ended {
+			t.Errorf("span '%s' is not ended", s.name)
+		}
+		if s.name != "test" && !s.within("test") {
+			t.Errorf("span '%s' is not nested into context span", s.name)
+		}
+	}
+	if len(gets) != 1 || gets[0].attrs["reuse"].Bool() {
+		t.Fatal("joint should be made on first access")
+	}
+	if !gets[0].within("JointPool.Open") || !gets[0].parent.within("JointPool.ReadDir") {
+		t.Fatal("getting of joint is not nested into pool operation")
+	}
+	var makes, readat int
+	for _, s := range all {
+		switch s.name {
+		case "Joint.Make":
+			if !s.within("MakeJoint") {
+				t.Error("making of joint is not nested into MakeJoint")
+			}
+			makes++
+		case "Joint.ReadAt":
+			if !s.within("Joint.Make") && !s.within("Joint.ReadDir") {
+				t.Error("reading of base joint is not nested into making of container or pool operation")
+			}
+			readat++
+		case "Joint.ReadDir":
+			if s.parent.name != "JointPool.ReadDir" {
+				t.Error("reading of directory is not nested into pool operation")
+			}
+		}
+	}
+	if makes != 2 || readat == 0 {
+		t.Fatalf("nested ISO-images resolution is not traced, %d makes, %d reads", makes, readat)
+	}
+
+	// second access reuses cached joint
+	ctx, span = tr.Start(context.Background(), "test2")
+	if _, err := jp.StatContext(ctx, "testdata/external.iso/disk/internal.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	span.End(nil)
+	var _, all2 = tr.take("")
+	for _, s := range all2 {
+		if s.name == "MakeJoint" {
+			t.Fatal("joint should be reused")
+		}
+		if s.name == "JointCache.Get" && !s.attrs["reuse"].Bool() {
+			t.Fatal("getting of joint is not marked as reuse")
+		}
+	}
+
+	// reading of file opened on cached joint is traced with calls to its bases
+	ctx, span = tr.Start(context.Background(), "test3")
+	var f, err = jp.OpenContext(ctx, "testdata/external.iso/disk/internal.iso/fox.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.take("")
+	if _, err = io.ReadAll(f); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	span.End(nil)
+	var reads, all3 = tr.take("Joint.Read")
+	if len(reads) == 0 || !reads[0].within("test3") {
+		t.Fatal("reading of file is not traced")
+	}
+	readat = 0
+	for _, s := range all3 {
+		if s.name == "Joint.ReadAt" {
+			if !s.within("Joint.Read") || !s.within("test3") {
+				t.Fatal("reading of base of cached joint is not nested into reading of file")
+			}
+			readat++
+		}
+	}
+	if readat == 0 {
+		t.Fatal("calls to bases of cached joint are not traced")
+	}
+
+	// failed operation ends span with error
+	if _, err := jp.StatContext(context.Background(), "testdata/external.iso/nothing.txt"); err == nil {
+		t.Fatal("file should not exist")
+	}
+	var stats, _ = tr.take("JointPool.Stat")
+	if len(stats) != 1 || stats[0].err == nil {
+		t.Fatal("failed operation should end span with error")
+	}
+}
+
+func TestDialTracer(t *testing.T) {
+	var tr = &rectracer{}
+	var jp = jnt.NewJointPool(jnt.WithTracer(tr))
+	defer jp.Close()
+
+	if _, err := jp.StatContext(context.Background(), srvs.FtpAddr+"/go.mod"); err != nil {
+		t.Fatal(err)
+	}
+	var _, all = tr.take("")
+	var stages []string
+	for _, s := range all {
+		if s.name == "Joint.Dial" || s.name == "Joint.Login" {
+			if s.parent == nil || s.parent.name != "Joint.Make" || !s.ended {
+				t.Errorf("span '%s' is not nested into making of joint", s.name)
+			}
+			stages = append(stages, s.name)
+		}
+	}
+	if len(stages) != 2 || stages[0] != "Joint.Dial" || stages[1] != "Joint.Login" {
+		t.Fatalf("dialing and login are not traced, got %v", stages)
+	}
+}
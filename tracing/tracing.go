@@ -0,0 +1,76 @@
+// Package tracing provides adapter of joint.Tracer interface
+// that starts spans of pool operations by OpenTelemetry tracer.
+package tracing
+
+import (
+	"context"
+	"log/slog"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name is name of instrumentation library used for default tracer.
+const Name = "github.com/schwarzlichtbezirk/joint"
+
+// OTel is joint.Tracer that starts OpenTelemetry spans.
+type OTel struct {
+	tracer trace.Tracer
+}
+
+// NewOTel returns tracer with given OpenTelemetry tracer. If it's nil,
+// tracer of global tracer provider is used.
+func NewOTel(tracer trace.Tracer) *OTel {
+	if tracer == nil {
+		tracer = otel.Tracer(Name)
+	}
+	return &OTel{tracer: tracer}
+}
+
+// Start implements joint.Tracer interface.
+func (t *OTel) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, jnt.Span) {
+	var span trace.Span
+	ctx, span = t.tracer.Start(ctx, name, trace.WithAttributes(keyvalues(attrs)...))
+	return ctx, otelspan{span}
+}
+
+// otelspan is joint.Span that wraps OpenTelemetry span.
+type otelspan struct {
+	span trace.Span
+}
+
+func (s otelspan) SetAttrs(attrs ...slog.Attr) {
+	s.span.SetAttributes(keyvalues(attrs)...)
+}
+
+func (s otelspan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+// keyvalues converts slog attributes to OpenTelemetry attributes.
+func keyvalues(attrs []slog.Attr) []attribute.KeyValue {
+	var kvs = make([]attribute.KeyValue, len(attrs))
+	for i, a := range attrs {
+		var v = a.Value.Resolve()
+		switch v.Kind() {
+		case slog.KindBool:
+			kvs[i] = attribute.Bool(a.Key, v.Bool())
+		case slog.KindInt64:
+			kvs[i] = attribute.Int64(a.Key, v.Int64())
+		case slog.KindUint64:
+			kvs[i] = attribute.Int64(a.Key, int64(v.Uint64()))
+		case slog.KindFloat64:
+			kvs[i] = attribute.Float64(a.Key, v.Float64())
+		default:
+			kvs[i] = attribute.String(a.Key, v.String())
+		}
+	}
+	return kvs
+}
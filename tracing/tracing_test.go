@@ -0,0 +1,59 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+	"github.com/schwarzlichtbezirk/joint/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOTel(t *testing.T) {
+	var rec = tracetest.NewSpanRecorder()
+	var tp = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	defer tp.Shutdown(context.Background())
+	var jp = jnt.NewJointPool(jnt.WithTracer(tracing.NewOTel(tp.Tracer("test"))))
+	defer jp.Close()
+
+	var ctx, root = tp.Tracer("test").Start(context.Background(), "request")
+	if _, err := jp.StatContext(ctx, "../testdata/external.iso/fox.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jp.StatContext(ctx, "../testdata/external.iso/nothing.txt"); err == nil {
+		t.Fatal("file should not exist")
+	}
+	root.End()
+
+	var names = map[string]int{}
+	var failed int
+	for _, s := range rec.Ended() {
+		names[s.Name()]++
+		if s.Name() != "request" && s.SpanContext().TraceID() != root.SpanContext().TraceID() {
+			t.Errorf("span '%s' has wrong trace", s.Name())
+		}
+		if s.Name() == "JointPool.Stat" && s.Status().Code == codes.Error {
+			failed++
+		}
+		if s.Name() == "JointCache.Get" {
+			var reuse bool
+			for _, kv := range s.Attributes() {
+				if kv.Key == attribute.Key("reuse") {
+					reuse = kv.Value.AsBool()
+				}
+			}
+			if reuse != (names[s.Name()] > 1) {
+				t.Errorf("JointCache.Get has wrong reuse attribute")
+			}
+		}
+	}
+	if names["JointPool.Stat"] != 2 || names["JointCache.Get"] != 2 || names["MakeJoint"] != 1 {
+		t.Fatalf("unexpected spans: %v", names)
+	}
+	if failed != 1 {
+		t.Fatalf("expected one failed span, got %d", failed)
+	}
+}
@@ -0,0 +1,237 @@
+package joint
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// DefWalkJoints is default number of joints used concurrently
+// by Walk for each joints cache.
+const DefWalkJoints = 4
+
+// WalkOptions determines how Walk traverses directories.
+type WalkOptions struct {
+	// Descend into containers such as ISO-images, disk images and archives.
+	Containers bool
+	// Glob patterns of reported files, all files are reported if it's empty.
+	// Pattern with slash is matched with path relative to walk root,
+	// otherwise with file name. Directories are traversed anyway.
+	Include []string
+	// Glob patterns of skipped files. Excluded directories are not traversed.
+	Exclude []string
+	// Maximum depth of reported files, root directory content has depth 1.
+	// Zero value means unlimited depth.
+	MaxDepth int
+	// Maximum number of directories read concurrently at each joints
+	// cache, i.e. at each FTP-server or ISO-image. DefWalkJoints is used
+	// if it's zero.
+	Joints int
+}
+
+// WalkEntry describes file found by Walk.
+type WalkEntry struct {
+	fs.DirEntry
+	Path  string // full path of file
+	Depth int    // depth from the walk root, root has zero depth
+	Err   error  // error of directory reading
+}
+
+// WalkFunc is called by Walk for each reported file. If it returns
+// fs.SkipDir for directory, Walk does not traverse this directory.
+// If it returns fs.SkipAll, or any other error, Walk stops.
+// If directory can not be read, function is called second time
+// for this directory with error at Err field.
+type WalkFunc func(ent WalkEntry) error
+
+// match checks up that file at given relative path matches to any of patterns.
+func match(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		var s = rel
+		if !strings.Contains(pattern, "/") {
+			s = path.Base(rel)
+		}
+		if ok, _ := path.Match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isrealdir returns true if directory entry points to real directory.
+func isrealdir(de fs.DirEntry) bool {
+	if jfi, ok := de.(interface{ IsRealDir() bool }); ok {
+		return jfi.IsRealDir()
+	}
+	return de.IsDir()
+}
+
+// walkres is the result of directory reading.
+type walkres struct {
+	de    fs.DirEntry
+	dir   string
+	key   string
+	depth int
+	list  []fs.DirEntry
+	err   error
+}
+
+// Walk traverses directories tree with given root concurrently, and
+// calls given function for each found file. Directories placed at the
+// same joints cache are read by several joints up to the limit of
+// options, so content of FTP-server or SFTP-server can be indexed much
+// faster than by fs.WalkDir. Files of each directory are reported in
+// lexical order, but order of directories is not determined. Function
+// is never called concurrently, and is called in the caller goroutine.
+func (jp *JointPool) Walk(ctx context.Context, root string, opts WalkOptions, fn WalkFunc) (err error) {
+	if len(root) > 1 {
+		root = strings.TrimSuffix(root, "/")
+	}
+	var fi fs.FileInfo
+	if fi, err = jp.StatContext(ctx, root); err != nil {
+		return
+	}
+	var de = ToDirEntry(fi)
+	if err = fn(WalkEntry{DirEntry: de, Path: root}); err != nil || !de.IsDir() {
+		if err == fs.SkipDir || err == fs.SkipAll {
+			err = nil
+		}
+		return
+	}
+
+	var limit = opts.Joints
+	if limit <= 0 {
+		limit = DefWalkJoints
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var results = make(chan walkres)
+	var queue []walkres // directories waiting for reading
+	var running = map[string]int{}
+	var count int
+	var launch = func() {
+		var i int
+		for _, t := range queue {
+			if running[t.key] >= limit {
+				queue[i] = t
+				i++
+				continue
+			}
+			running[t.key]++
+			count++
+			go func(t walkres) {
+				t.list, t.err = jp.ReadDirContext(ctx, t.dir)
+				select {
+				case results <- t:
+				case <-ctx.Done():
+				}
+			}(t)
+		}
+		clear(queue[i:])
+		queue = queue[:i]
+	}
+	var push = func(de fs.DirEntry, dir string, depth int) {
+		var key, _, _ = splitkey(&jp.sniffed, dir, len(dir))
+		queue = append(queue, walkres{de: de, dir: dir, key: key, depth: depth})
+	}
+
+	push(de, root, 0)
+	launch()
+	for count > 0 {
+		var r walkres
+		select {
+		case r = <-results:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		running[r.key]--
+		count--
+
+		if r.err != nil {
+			if err = fn(WalkEntry{DirEntry: r.de, Path: r.dir, Depth: r.depth, Err: r.err}); err == fs.SkipDir {
+				err = nil
+			}
+			if err != nil {
+				break
+			}
+		}
+		var depth = r.depth + 1
+		for _, de := range r.list {
+			var fpath = JoinPath(r.dir, de.Name())
+			var rel = strings.TrimPrefix(fpath, JoinPath(root, "/"))
+			if match(opts.Exclude, rel) {
+				continue
+			}
+			var descend = de.IsDir() && (opts.Containers || isrealdir(de)) &&
+				(opts.MaxDepth == 0 || depth < opts.MaxDepth)
+			if len(opts.Include) == 0 || match(opts.Include, rel) {
+				err = fn(WalkEntry{DirEntry: de, Path: fpath, Depth: depth})
+				if err == fs.SkipDir {
+					err, descend = nil, false
+				}
+				if err != nil {
+					break
+				}
+			}
+			if descend {
+				push(de, fpath, depth)
+			}
+		}
+		if err != nil {
+			break
+		}
+		launch()
+	}
+	if errors.Is(err, fs.SkipAll) {
+		err = nil
+	}
+	return
+}
+
+// WalkChan is the same as Walk, but streams found files over returned
+// channel. Channel is closed when traverse is finished or given context
+// is canceled. Errors of directories reading are passed in entries.
+func (jp *JointPool) WalkChan(ctx context.Context, root string, opts WalkOptions) <-chan WalkEntry {
+	var ch = make(chan WalkEntry)
+	go func() {
+		defer close(ch)
+		var err = jp.Walk(ctx, root, opts, func(ent WalkEntry) error {
+			select {
+			case ch <- ent:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			select {
+			case ch <- WalkEntry{Path: root, Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return ch
+}
+
+// Walk is the same as JointPool.Walk, but receives path relative
+// to subsystem root, and reports relative paths.
+func (sp *SubPool) Walk(ctx context.Context, root string, opts WalkOptions, fn WalkFunc) error {
+	if sp.dir == "" || sp.dir == "." {
+		return sp.JointPool.Walk(ctx, root, opts, fn)
+	}
+	if !fs.ValidPath(root) {
+		return fs.ErrInvalid
+	}
+	var dir = strings.TrimSuffix(sp.dir, "/")
+	return sp.JointPool.Walk(ctx, JoinPath(dir, root), opts, func(ent WalkEntry) error {
+		if ent.Path == dir {
+			ent.Path = "."
+		} else {
+			ent.Path = strings.TrimPrefix(ent.Path, dir+"/")
+		}
+		return fn(ent)
+	})
+}
@@ -0,0 +1,160 @@
+package joint_test
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+
+	jnt "github.com/schwarzlichtbezirk/joint"
+)
+
+func walkPaths(t *testing.T, jp *jnt.JointPool, root string, opts jnt.WalkOptions) map[string]int {
+	var found = map[string]int{}
+	var err = jp.Walk(context.Background(), root, opts, func(ent jnt.WalkEntry) error {
+		if ent.Err != nil {
+			return ent.Err
+		}
+		if _, ok := found[ent.Path]; ok {
+			t.Fatalf("%s: file reported twice", ent.Path)
+		}
+		found[ent.Path] = ent.Depth
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
+
+func TestWalk(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var found = walkPaths(t, jp, "testdata", jnt.WalkOptions{})
+	if _, ok := found["testdata/external.iso"]; !ok {
+		t.Fatal("container file is not reported")
+	}
+	if _, ok := found["testdata/external.iso/fox.txt"]; ok {
+		t.Fatal("container should not be traversed without option")
+	}
+
+	found = walkPaths(t, jp, "testdata", jnt.WalkOptions{Containers: true})
+	for _, fpath := range []string{
+		"testdata/external.iso/disk/internal.iso/fox.txt",
+		"testdata/archive.zip/data/docs/doc1.txt",
+		"testdata/disk.img/p1/DCIM/100CANON/IMG_0001.TXT",
+		"testdata/disk.img/p3/disk/internal.iso/docs/doc2.txt",
+	} {
+		if _, ok := found[fpath]; !ok {
+			t.Fatalf("%s: file is not reported", fpath)
+		}
+	}
+	if found["testdata/external.iso/disk/internal.iso/fox.txt"] != 4 {
+		t.Fatal("depth of file does not match")
+	}
+}
+
+func TestWalkFilter(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var found = walkPaths(t, jp, "testdata", jnt.WalkOptions{
+		Containers: true,
+		Include:    []string{"*.txt"},
+		Exclude:    []string{"*.rar", "*.7z", "disk.img/p2"},
+		MaxDepth:   3,
+	})
+	if len(found) < 2 {
+		t.Fatal("files are not found")
+	}
+	for fpath, depth := range found {
+		if fpath == "testdata" {
+			continue
+		}
+		if path.Ext(fpath) != ".txt" {
+			t.Fatalf("%s: file does not match to include patterns", fpath)
+		}
+		if strings.Contains(fpath, ".rar/") || strings.Contains(fpath, ".7z/") || strings.Contains(fpath, "/p2/") {
+			t.Fatalf("%s: file matches to exclude patterns", fpath)
+		}
+		if depth > 3 {
+			t.Fatalf("%s: file is deeper than limit", fpath)
+		}
+	}
+}
+
+// Walk should report the same files as fs.WalkDir.
+func TestWalkDir(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var sp, err = jp.Sub("testdata/external.iso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected = map[string]bool{}
+	if err = fs.WalkDir(sp, ".", func(fpath string, d fs.DirEntry, err error) error {
+		expected[fpath] = true
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var found = map[string]bool{}
+	if err = sp.(*jnt.SubPool).Walk(context.Background(), ".", jnt.WalkOptions{
+		Containers: true,
+		Joints:     2,
+	}, func(ent jnt.WalkEntry) error {
+		found[ent.Path] = true
+		return ent.Err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != len(expected) {
+		t.Fatalf("expected %d files, found %d", len(expected), len(found))
+	}
+	for fpath := range expected {
+		if !found[fpath] {
+			t.Fatalf("%s: file is not reported", fpath)
+		}
+	}
+	if n := jp.GetCache("testdata/external.iso").Count(); n > 2 {
+		t.Fatalf("expected no more than 2 joints in cache, found %d", n)
+	}
+}
+
+func TestWalkStop(t *testing.T) {
+	var jp = jnt.NewJointPool()
+	defer jp.Close()
+
+	var n int
+	var err = jp.Walk(context.Background(), "testdata", jnt.WalkOptions{Containers: true}, func(ent jnt.WalkEntry) error {
+		if n++; n == 5 {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Fatal("walk is not stopped")
+	}
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	var count int
+	for ent := range jp.WalkChan(ctx, "testdata", jnt.WalkOptions{Containers: true}) {
+		if ent.Err != nil {
+			t.Fatal(ent.Err)
+		}
+		if count++; count == 5 {
+			cancel()
+		}
+	}
+	cancel()
+	if count < 5 {
+		t.Fatal("not all files are received")
+	}
+}